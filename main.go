@@ -8,19 +8,63 @@
 // there was any errors, exit code will be non-zero and errors will be printed
 // to stderr.
 //
+// More than one --input can be given, and so can paths after the flags. Each
+// of them can be a file, a directory to search for .json files (.jsonl and
+// .ndjson with --ndjson) or a glob pattern like "testdata/*.json". Nothing is
+// validated if any directory or pattern doesn't lead to any files. The schema
+// is compiled once for all of them, errors are printed prefixed with the name
+// of the file, followed by the number of valid and invalid files, and exit
+// status is non-zero if any of them is invalid. With --output, the results are
+// printed as an object with the file names as keys.
+//
+// Either the schema or the input can be read from stdin by passing "-" instead
+// of the path:
+//
+//   some-generator | validate-json --schema path/to/schema.json --input -
+//
 // Additional flags:
 //
 //   --extra "schema1.json schema2.json ..."
 // Space-separated list of additional schema files to load so they can be
 // referenced from the primary schema. Each of the schemas in these files needs
-// to have "id" set.
+// to have "id" (or "$id" since draft 06) set.
 //
 //   -n
 // If present, referenced schemas will be fetched from the remote hosts.
 //
 //   -nodraft04schema
-// If present, copy of http://json-schema.org/draft-04/schema embedded in the
-// binary will not be pre-loaded.
+// If present, copies of http://json-schema.org/draft-04/schema,
+// http://json-schema.org/draft-06/schema,
+// http://json-schema.org/draft-07/schema,
+// https://json-schema.org/draft/2019-09/schema and
+// https://json-schema.org/draft/2020-12/schema embedded in the binary will not
+// be pre-loaded.
+//
+//   --draft draft-07
+// Dialect to use for the schema regardless of its "$schema": one of draft-04,
+// draft-06, draft-07, 2019-09 and 2020-12, or the URI of its meta-schema.
+//
+//   --output basic
+// Print the result to stdout as JSON in one of the standard output formats:
+// flag, basic, detailed or verbose. Exit status is still non-zero if the data
+// is not valid, but nothing is printed to stderr about it.
+//
+//   --stream
+// Validate the input while reading it, without keeping all of it in memory,
+// which is meant for very large files. Can't be used with --output.
+//
+//   --ndjson
+// Treat every non-empty line of the input as a separate value, as in JSON Lines
+// and NDJSON files. Errors are printed to stderr prefixed with the line
+// number, followed by the number of valid and invalid lines. Exit status is
+// non-zero if any of the lines is invalid. Can't be used with --output or
+// --stream.
+//
+//   --workers 4
+// Number of lines to validate at once with --ndjson. Defaults to 1.
+//
+// The schema is validated as draft 04 unless its "$schema" says it is draft 06,
+// draft 07, 2019-09 or 2020-12.
 package main
 
 // go get github.com/jteeuwen/go-bindata/go-bindata
@@ -28,9 +72,14 @@ package main
 
 import (
 	"bytes"
+	"context"
+	stdjson "encoding/json"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"strings"
 
 	json "github.com/cesanta/ucl"
@@ -38,34 +87,124 @@ import (
 )
 
 var (
-	schemaFile        = flag.String("schema", "", "Path to schema to use.")
-	inputFile         = flag.String("input", "", "Path to the JSON data to validate.")
+	inputs            inputList
+	schemaFile        = flag.String("schema", "", "Path to schema to use, or \"-\" to read it from stdin.")
 	network           = flag.Bool("n", false, "If true, fetching of referred schemas from remote hosts will be enabled.")
-	extra             = flag.String("extra", "", "Space-separated list of schema files to pre-load for the purpose of remote references. Each schema needs to have 'id' property.")
-	skipDefaultSchema = flag.Bool("nodraft04schema", false, "If set to true, http://json-schema.org/draft-04/schema will not be pre-loaded.")
+	extra             = flag.String("extra", "", "Space-separated list of schema files to pre-load for the purpose of remote references. Each schema needs to have 'id' or '$id' property.")
+	draft             = flag.String("draft", "", "Dialect to use instead of the one named by \"$schema\" in the schema: draft-04, draft-06, draft-07, 2019-09 or 2020-12.")
+	output            = flag.String("output", "", "If set, print the result to stdout in one of the standard JSON Schema output formats: flag, basic, detailed or verbose.")
+	stream            = flag.Bool("stream", false, "If set, the input is validated while it is read, without keeping all of it in memory.")
+	ndjson            = flag.Bool("ndjson", false, "If set, every non-empty line of the input is validated as a separate value.")
+	workers           = flag.Int("workers", 1, "Number of lines to validate at once with --ndjson.")
+	skipDefaultSchema = flag.Bool("nodraft04schema", false, "If set to true, embedded copies of the draft-04, draft-06, draft-07, 2019-09 and 2020-12 meta-schemas will not be pre-loaded.")
 )
 
+// metaSchemas maps dialects to the names of their meta-schemas embedded with
+// bindata. The first one is the meta-schema itself, the rest are the documents
+// it refers to.
+var metaSchemas = map[*schema.Dialect][]string{
+	schema.Draft04: {"draft04schema.json"},
+	schema.Draft06: {"draft06schema.json"},
+	schema.Draft07: {"draft07schema.json"},
+	schema.Draft201909: {
+		"draft201909schema.json",
+		"draft201909core.json",
+		"draft201909applicator.json",
+		"draft201909validation.json",
+		"draft201909metadata.json",
+		"draft201909format.json",
+		"draft201909content.json",
+	},
+	schema.Draft202012: {
+		"draft202012schema.json",
+		"draft202012core.json",
+		"draft202012applicator.json",
+		"draft202012unevaluated.json",
+		"draft202012validation.json",
+		"draft202012metadata.json",
+		"draft202012formatannotation.json",
+		"draft202012content.json",
+	},
+}
+
+// outputFormats maps names of the standard output formats to the functions
+// producing them.
+var outputFormats = map[string]func(*schema.Validator, json.Value) (interface{}, bool){
+	"flag": func(v *schema.Validator, data json.Value) (interface{}, bool) {
+		r := v.FlagOutput(data)
+		return r, r.Valid
+	},
+	"basic": func(v *schema.Validator, data json.Value) (interface{}, bool) {
+		r := v.BasicOutput(data)
+		return r, r.Valid
+	},
+	"detailed": func(v *schema.Validator, data json.Value) (interface{}, bool) {
+		r := v.DetailedOutput(data)
+		return r, r.Valid
+	},
+	"verbose": func(v *schema.Validator, data json.Value) (interface{}, bool) {
+		r := v.VerboseOutput(data)
+		return r, r.Valid
+	},
+}
+
 func main() {
+	flag.Var(&inputs, "input", "Path to the JSON data to validate, \"-\" to read it from stdin, a directory with it or a glob pattern. Can be given more than once.")
 	flag.Parse()
 
-	if *schemaFile == "" || *inputFile == "" {
+	if *schemaFile == "" || len(inputs)+flag.NArg() == 0 {
 		fmt.Fprintf(os.Stderr, "Need --schema and --input\n")
 		os.Exit(1)
 	}
+	if _, found := outputFormats[*output]; *output != "" && !found {
+		fmt.Fprintf(os.Stderr, "Unknown --output %q\n", *output)
+		os.Exit(1)
+	}
+	if *stream && *output != "" {
+		fmt.Fprintf(os.Stderr, "--stream can't be used with --output\n")
+		os.Exit(1)
+	}
+	if *ndjson && (*output != "" || *stream) {
+		fmt.Fprintf(os.Stderr, "--ndjson can't be used with --output or --stream\n")
+		os.Exit(1)
+	}
+
+	if n := countStdin(append(inputs, flag.Args()...)); n > 1 || n == 1 && *schemaFile == "-" {
+		fmt.Fprintf(os.Stderr, "Only one of --schema and --input can be \"-\"\n")
+		os.Exit(1)
+	}
 
-	f, err := os.Open(*schemaFile)
+	f, err := openFile(*schemaFile)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to open %q: %s\n", *schemaFile, err)
 		os.Exit(1)
 	}
 
-	s, err := json.Parse(f)
+	s, err := schema.Parse(f)
 	f.Close()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to read schema: %s\n", err)
 		os.Exit(1)
 	}
 
+	dialect := schema.DetectDialect(s)
+	var opts []schema.Option
+	if *draft != "" {
+		dialect = schema.DialectByName(*draft)
+		if dialect == nil {
+			fmt.Fprintf(os.Stderr, "Unknown --draft %q\n", *draft)
+			os.Exit(1)
+		}
+		opts = append(opts, schema.OverrideDialect(dialect))
+	}
+	if obj, ok := s.(*json.Object); ok && dialect == nil {
+		// Schemas with "$schema" set to a custom meta-schema are not
+		// checked against any of the embedded ones.
+		if _, found := obj.Lookup("$schema"); !found {
+			dialect = schema.Draft04
+		}
+	}
+
 	loader := schema.NewLoader()
 	loader.EnableNetworkAccess(*network)
 	if *extra != "" {
@@ -75,7 +214,7 @@ func main() {
 				fmt.Fprintf(os.Stderr, "Failed to open %q: %s\n", file, err)
 				os.Exit(1)
 			}
-			s, err := json.Parse(f)
+			s, err := schema.Parse(f)
 			f.Close()
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Failed to parse %q: %s\n", file, err)
@@ -85,42 +224,215 @@ func main() {
 		}
 	}
 	if !*skipDefaultSchema {
-		ds, err := json.Parse(bytes.NewBuffer(MustAsset("draft04schema.json")))
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to parse embedded draft 04 schema: %s\n", err)
-			os.Exit(1)
+		var metaSchema json.Value
+		for d, names := range metaSchemas {
+			for i, name := range names {
+				ds, err := schema.Parse(bytes.NewBuffer(MustAsset(name)))
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to parse embedded %s schema %q: %s\n", d, name, err)
+					os.Exit(1)
+				}
+				if i > 0 {
+					loader.Add(ds)
+					continue
+				}
+				loader.AddAs(ds, d.URI())
+				if d == dialect {
+					metaSchema = ds
+				}
+			}
 		}
-		// Just to be sure, schema.ParseDraft04Schema exercises different code path.
-		v, err := schema.NewValidator(ds, loader)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to create validator for draft04 schema, please file a bug: %s\n", err)
-		} else {
-			if err := v.Validate(s); err != nil {
+		// Just to be sure, NewValidator checks the schema with a different code
+		// path. Custom meta-schemas are not embedded, so those are skipped.
+		if metaSchema != nil {
+			v, err := schema.NewValidator(metaSchema, loader)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to create validator for %s schema, please file a bug: %s\n", dialect, err)
+			} else if err := v.Validate(s); err != nil {
 				fmt.Fprintln(os.Stderr, "If you see this message, please file a bug and attach the schema you're using.")
-				fmt.Fprintf(os.Stderr, "Warning: failed to validate %q with draft 04 schema: %s\n", *schemaFile, err)
+				fmt.Fprintf(os.Stderr, "Warning: failed to validate %q with %s schema: %s\n", *schemaFile, dialect, err)
 			}
 		}
 	}
 
-	validator, err := schema.NewValidator(s, loader)
+	if *stream {
+		// Like Validate, stop at the first violation.
+		opts = append(opts, schema.WithLimits(schema.Limits{MaxErrors: 1}))
+	}
+	validator, err := schema.NewValidator(s, loader, opts...)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to create validator: %s\n", err)
 		os.Exit(1)
 	}
 
-	f, err = os.Open(*inputFile)
+	files, err := expandInputs(append(inputs, flag.Args()...))
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to open input file: %s\n", err)
+		fmt.Fprintf(os.Stderr, "Failed to find input files: %s\n", err)
+		os.Exit(1)
+	}
+	results := map[string]interface{}{}
+	invalid := 0
+	for _, file := range files {
+		prefix := ""
+		if len(files) > 1 {
+			prefix = file + ": "
+		}
+		r, valid := validateFile(validator, file, prefix)
+		results[file] = r
+		if !valid {
+			invalid++
+		}
+	}
+	if *output != "" {
+		var r interface{} = results
+		if len(files) == 1 {
+			r = results[files[0]]
+		}
+		b, err := stdjson.MarshalIndent(r, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encode the result: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s\n", b)
+	}
+	if len(files) > 1 {
+		fmt.Fprintf(os.Stderr, "%d files valid, %d invalid\n", len(files)-invalid, invalid)
+	}
+	if invalid > 0 {
 		os.Exit(1)
 	}
+}
+
+// inputList collects the values of --input, which can be given more than once.
+type inputList []string
+
+func (l *inputList) String() string {
+	return strings.Join(*l, " ")
+}
+
+func (l *inputList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// expandInputs returns the files named by args, which are paths to files or
+// directories, or glob patterns. Directories are searched recursively for
+// files with the extensions of JSON, or JSON Lines with --ndjson.
+func expandInputs(args []string) ([]string, error) {
+	exts := []string{".json"}
+	if *ndjson {
+		exts = []string{".jsonl", ".ndjson"}
+	}
+	var files []string
+	for _, arg := range args {
+		paths := []string{arg}
+		if strings.ContainsAny(arg, "*?[") {
+			matches, err := filepath.Glob(arg)
+			if err != nil {
+				return nil, fmt.Errorf("bad pattern %q: %s", arg, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match %q", arg)
+			}
+			paths = matches
+		}
+		for _, path := range paths {
+			if fi, err := os.Stat(path); err != nil || !fi.IsDir() {
+				// Errors are reported when the file is opened.
+				files = append(files, path)
+				continue
+			}
+			found := len(files)
+			err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				for _, ext := range exts {
+					if !d.IsDir() && strings.HasSuffix(p, ext) {
+						files = append(files, p)
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %q: %s", path, err)
+			}
+			if len(files) == found {
+				return nil, fmt.Errorf("no %s files in %q", strings.Join(exts, " or "), path)
+			}
+		}
+	}
+	return files, nil
+}
+
+// openFile opens the file at path, or returns stdin if path is "-".
+func openFile(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+// countStdin returns how many of paths are "-", standing for stdin.
+func countStdin(paths []string) int {
+	n := 0
+	for _, path := range paths {
+		if path == "-" {
+			n++
+		}
+	}
+	return n
+}
+
+// validateFile validates the contents of the file at path and prints the
+// errors, prefixed with prefix, to stderr. It returns the result in the
+// --output format, if it is set, and whether the contents are valid.
+func validateFile(validator *schema.Validator, path, prefix string) (interface{}, bool) {
+	f, err := openFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%sFailed to open input file: %s\n", prefix, err)
+		return nil, false
+	}
 	defer f.Close()
-	data, err := json.Parse(f)
+	if *ndjson {
+		valid, invalid, err := validateLines(validator, f, *workers, func(r lineResult) {
+			if r.err != nil {
+				fmt.Fprintf(os.Stderr, "%sline %d: %s\n", prefix, r.line, r.err)
+			}
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%sFailed to read input file: %s\n", prefix, err)
+			return nil, false
+		}
+		fmt.Fprintf(os.Stderr, "%s%d valid, %d invalid\n", prefix, valid, invalid)
+		return nil, invalid == 0
+	}
+	if *stream {
+		err := validator.ValidateReader(context.Background(), f)
+		if ie, ok := err.(*schema.IncompleteError); ok {
+			err = ie.Errors
+		}
+		if _, ok := err.(schema.Errors); ok {
+			fmt.Fprintf(os.Stderr, "%s%s\n", prefix, err)
+			return nil, false
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%sFailed to parse input file: %s\n", prefix, err)
+			return nil, false
+		}
+		return nil, true
+	}
+	data, err := schema.Parse(f)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to parse input file: %s\n", err)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "%sFailed to parse input file: %s\n", prefix, err)
+		return nil, false
+	}
+	if *output != "" {
+		return outputFormats[*output](validator, data)
 	}
 	if err := validator.Validate(data); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "%s%s\n", prefix, err)
+		return nil, false
 	}
+	return nil, true
 }
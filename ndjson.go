@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/cesanta/validate-json/schema"
+)
+
+// lineResult is the outcome of validating one line of NDJSON input.
+type lineResult struct {
+	line int
+	err  error
+}
+
+// validateLines validates every non-empty line of r as a separate value with
+// up to workers goroutines and calls report with the results in the order of
+// the lines. It returns the number of lines that are valid and the number of
+// lines that are not.
+func validateLines(v *schema.Validator, r io.Reader, workers int, report func(lineResult)) (valid, invalid int, err error) {
+	if workers < 1 {
+		workers = 1
+	}
+	type job struct {
+		line   int
+		data   []byte
+		result chan lineResult
+	}
+	jobs := make(chan job)
+	// Results are reported in the order the lines were read, and there are at
+	// most 2*workers lines being validated or waiting to be reported.
+	pending := make(chan chan lineResult, 2*workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for j := range jobs {
+				j.result <- lineResult{line: j.line, err: validateLine(v, j.data)}
+			}
+		}()
+	}
+
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(pending)
+		defer close(jobs)
+		br := bufio.NewReader(r)
+		for line := 1; ; line++ {
+			data, err := br.ReadBytes('\n')
+			if len(bytes.TrimSpace(data)) > 0 {
+				result := make(chan lineResult, 1)
+				pending <- result
+				jobs <- job{line: line, data: data, result: result}
+			}
+			if err != nil {
+				if err == io.EOF {
+					err = nil
+				}
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	for result := range pending {
+		res := <-result
+		if res.err != nil {
+			invalid++
+		} else {
+			valid++
+		}
+		report(res)
+	}
+	return valid, invalid, <-readErr
+}
+
+// validateLine parses data and validates it.
+func validateLine(v *schema.Validator, data []byte) error {
+	val, err := schema.Parse(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to parse: %s", err)
+	}
+	return v.Validate(val)
+}
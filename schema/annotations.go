@@ -0,0 +1,110 @@
+package schema
+
+import (
+	"strings"
+
+	json "github.com/cesanta/ucl"
+)
+
+// annotationKeywords are the keywords that only describe the value. Keywords
+// starting with "x-" are collected too.
+var annotationKeywords = map[string]bool{
+	"title":       true,
+	"description": true,
+	"default":     true,
+	"examples":    true,
+	"readOnly":    true,
+	"writeOnly":   true,
+	"deprecated":  true,
+}
+
+// Annotation is the value of an annotation keyword, like "title", in a schema
+// that applied to a value successfully.
+type Annotation struct {
+	// InstancePath is a JSON Pointer to the value, "" being the whole value.
+	InstancePath string
+	// SchemaPath is the location of the keyword, like
+	// ValidationError.SchemaPath.
+	SchemaPath string
+	Keyword    string
+	Value      json.Value
+}
+
+// Result holds everything found by Validator.Annotate.
+type Result struct {
+	// Errors are all the violations, empty if the value is valid.
+	Errors Errors
+	// Annotations maps InstancePath to the annotations that apply to the
+	// value there, in the order the schemas were applied.
+	Annotations map[string][]*Annotation
+}
+
+// Valid reports whether the value is valid.
+func (r *Result) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// Annotate validates val like ValidateAll and collects the annotations from
+// all the schemas val, its items and its properties are valid against. If a
+// schema fails, annotations for the value it is applied to are dropped, both
+// its own and the ones from its subschemas, but the ones for the items and
+// properties that are valid on their own are kept. Annotations from
+// subschemas applied only to see if the value is valid against them, like
+// the ones in "anyOf", are dropped entirely if the value is not valid.
+func (v *Validator) Annotate(val json.Value) *Result {
+	s := v.newState(0)
+	s.annotate = true
+	s.validate(v.root, "", val)
+	r := &Result{Errors: Errors(s.errs.errs), Annotations: map[string][]*Annotation{}}
+	for _, a := range s.annotations {
+		r.Annotations[a.InstancePath] = append(r.Annotations[a.InstancePath], a)
+	}
+	return r
+}
+
+type annotation struct {
+	keyword string
+	value   json.Value
+}
+
+// compileAnnotations returns the annotation keywords of the schema, except for
+// the custom ones, which add annotations on their own.
+func compileAnnotations(schema *json.Object, custom map[string]Keyword) []annotation {
+	var r []annotation
+	for _, k := range sortedKeys(schema) {
+		if _, found := custom[k]; found {
+			continue
+		}
+		if annotationKeywords[k] || strings.HasPrefix(k, "x-") {
+			r = append(r, annotation{keyword: k, value: schema.Find(k)})
+		}
+	}
+	return r
+}
+
+// collect records the annotations of n, which is applied to the value at
+// path, and returns the function to call once n is done. If the value is not
+// valid against n, annotations for it collected since then are dropped.
+func (s *state) collect(n *node, path string) func() {
+	start, errs := len(s.annotations), len(s.errs.errs)
+	for _, a := range n.annotations {
+		s.annotations = append(s.annotations, &Annotation{
+			InstancePath: path,
+			SchemaPath:   n.path + "/" + escapeRefToken(a.keyword),
+			Keyword:      a.keyword,
+			Value:        a.value,
+		})
+	}
+	return func() {
+		if len(s.errs.errs) == errs {
+			return
+		}
+		kept := s.annotations[:start]
+		for _, a := range s.annotations[start:] {
+			if a.InstancePath != path {
+				kept = append(kept, a)
+			}
+		}
+		s.annotations = kept
+	}
+}
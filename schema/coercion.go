@@ -0,0 +1,154 @@
+package schema
+
+import (
+	"regexp"
+	"strconv"
+
+	json "github.com/cesanta/ucl"
+)
+
+// Coerce returns a copy of val with the values converted to the types their
+// schemas require with "type", if they are not of that type already, and
+// validates the copy like ValidateAll does. Strings written like JSON numbers
+// are converted to numbers, "true" and "false" to booleans and "" to null,
+// numbers and booleans to strings, null to "", and anything but an array to
+// an array with a single item. Values that can't be converted are left as
+// is, so they fail validation. Types required by subschemas applied only to
+// see if the value is valid against them, like the ones in "anyOf", are
+// ignored.
+func (v *Validator) Coerce(val json.Value) (json.Value, error) {
+	val = coerce(v.root, copyValue(val))
+	s := v.newState(0)
+	s.coerce = true
+	if err := s.validate(v.root, "", val); err != nil && err != errTooManyErrors {
+		return val, err
+	}
+	if len(s.errs.errs) > 0 {
+		return val, Errors(s.errs.errs)
+	}
+	return val, nil
+}
+
+// coerceItems converts the items of val to the types required by the schemas
+// in "items" of n.
+func coerceItems(n *node, val *json.Array) {
+	for i, item := range val.Value {
+		var sub *node
+		switch {
+		case n.items != nil:
+			sub = n.items
+		case i < len(n.itemsList):
+			sub = n.itemsList[i]
+		case n.additionalItems != nil:
+			sub = n.additionalItems
+		}
+		if sub != nil {
+			val.Value[i] = coerce(sub, item)
+		}
+	}
+}
+
+// coerceProperties converts the properties of val to the types required by
+// the schemas in "properties", "patternProperties" or "additionalProperties"
+// of n. Properties with names that can't be matched against a regexp in
+// "patternProperties" are left as is, since it is not known which schemas
+// apply to them, and validation reports the error.
+func coerceProperties(n *node, val *json.Object) {
+properties:
+	for k, prop := range val.Value {
+		sub := n.properties[k.Value]
+		for _, pp := range n.patternProperties {
+			if sub != nil {
+				break
+			}
+			ok, err := pp.re.MatchString(k.Value)
+			if err != nil {
+				continue properties
+			}
+			if ok {
+				sub = pp.schema
+			}
+		}
+		if sub == nil {
+			sub = n.additionalProperties
+		}
+		if sub != nil {
+			val.Value[k] = coerce(sub, prop)
+		}
+	}
+}
+
+// requiredTypes returns the types required by n, or by the schema it refers
+// to with "$ref" if n itself doesn't have "type".
+func (n *node) requiredTypes() []string {
+	if len(n.types) == 0 && n.ref != nil {
+		// Chains of "$ref"s always end, see findCycle.
+		return n.ref.requiredTypes()
+	}
+	return n.types
+}
+
+// coerce returns val converted to the first of the types required by n it
+// can be converted to, or val itself if it already has one of them or can't
+// be converted.
+func coerce(n *node, val json.Value) json.Value {
+	types := n.requiredTypes()
+	for _, t := range types {
+		if isOfType(n.dialect, val, t) {
+			return val
+		}
+	}
+	for _, t := range types {
+		if r := convert(n.dialect, val, t); r != nil {
+			return r
+		}
+	}
+	return val
+}
+
+// jsonNumberRe matches numbers as JSON writes them.
+var jsonNumberRe = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// convert returns val converted to type t, or nil if it can't be converted.
+// Whether a number is an integer is up to d.
+func convert(d *Dialect, val json.Value, t string) json.Value {
+	switch t {
+	case "array":
+		if _, ok := val.(*json.Array); !ok {
+			return &json.Array{Value: []json.Value{val}}
+		}
+	case "string":
+		switch val := val.(type) {
+		case *json.Integer, *json.Number, *Decimal:
+			return &json.String{Value: formatNumber(val)}
+		case *json.Bool:
+			return &json.String{Value: strconv.FormatBool(val.Value)}
+		case *json.Null:
+			return &json.String{Value: ""}
+		}
+	}
+	s, ok := val.(*json.String)
+	if !ok {
+		return nil
+	}
+	switch t {
+	case "integer", "number":
+		if !jsonNumberRe.MatchString(s.Value) {
+			return nil
+		}
+		n, err := parseNumber(s.Value)
+		if err != nil || !isOfType(d, n, t) {
+			return nil
+		}
+		return n
+	case "boolean":
+		if s.Value == "true" || s.Value == "false" {
+			return &json.Bool{Value: s.Value == "true"}
+		}
+	case "null":
+		if s.Value == "" {
+			return &json.Null{}
+		}
+	}
+	return nil
+}
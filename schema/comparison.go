@@ -1,7 +1,10 @@
 package schema
 
 import (
+	stdjson "encoding/json"
 	"fmt"
+	"math/big"
+	"strconv"
 
 	json "github.com/cesanta/ucl"
 )
@@ -28,21 +31,10 @@ func equal(a json.Value, b json.Value) bool {
 			return false
 		}
 		return x.Value == b.Value
-	case *json.Number:
-		switch b := b.(type) {
-		case *json.Number:
-			return x.Value == b.Value // XXX: comparing floating point numbers.
-		case *json.Integer:
-			return x.Value == float64(b.Value) // XXX: comparing floating point numbers.
-		default:
-			return false
-		}
-	case *json.Integer:
-		switch b := b.(type) {
-		case *json.Number:
-			return float64(x.Value) == b.Value // XXX: comparing floating point numbers.
-		case *json.Integer:
-			return x.Value == b.Value
+	case *json.Number, *json.Integer, *Decimal:
+		switch b.(type) {
+		case *json.Number, *json.Integer, *Decimal:
+			return compareNumbers(a, b) == 0
 		default:
 			return false
 		}
@@ -77,13 +69,122 @@ func equal(a json.Value, b json.Value) bool {
 	}
 }
 
-func uniqueItems(val *json.Array) error {
+// findDuplicate returns indices of the first pair of equal items in val.
+func findDuplicate(val *json.Array) (int, int, bool) {
 	for i := range val.Value {
 		for j := i + 1; j < len(val.Value); j++ {
 			if equal(val.Value[i], val.Value[j]) {
-				return fmt.Errorf("all items must be unique, but item %d is equal to item %d", i, j)
+				return i, j, true
 			}
 		}
 	}
+	return 0, 0, false
+}
+
+func uniqueItems(val *json.Array) error {
+	if i, j, found := findDuplicate(val); found {
+		return fmt.Errorf("all items must be unique, but item %d is equal to item %d", i, j)
+	}
 	return nil
 }
+
+// compareNumbers returns -1, 0 or 1 if a is less than, equal to or greater
+// than b. Both must be *json.Integer, *json.Number or *Decimal.
+func compareNumbers(a json.Value, b json.Value) int {
+	switch x := a.(type) {
+	case *json.Integer:
+		if y, ok := b.(*json.Integer); ok {
+			switch {
+			case x.Value < y.Value:
+				return -1
+			case x.Value > y.Value:
+				return 1
+			}
+			return 0
+		}
+	case *json.Number:
+		// Comparing floats is exact, same as comparing the decimals they
+		// came from, see toRat.
+		if y, ok := b.(*json.Number); ok {
+			switch {
+			case x.Value < y.Value:
+				return -1
+			case x.Value > y.Value:
+				return 1
+			}
+			return 0
+		}
+	}
+	// Not every int64 fits into float64.
+	return toRat(a).Cmp(toRat(b))
+}
+
+func sign(a json.Value) int {
+	return compareNumbers(a, &json.Integer{Value: 0})
+}
+
+func isMultipleOf(a json.Value, b json.Value) bool {
+	if x, ok := a.(*json.Integer); ok {
+		if y, ok := b.(*json.Integer); ok {
+			return x.Value%y.Value == 0
+		}
+	}
+	// Dividing floats is not exact, e.g. 0.07 is not a multiple of 0.01 that
+	// way.
+	return new(big.Rat).Quo(toRat(a), toRat(b)).IsInt()
+}
+
+// toRat returns the number as an exact fraction, which must not be modified.
+func toRat(a json.Value) *big.Rat {
+	switch a := a.(type) {
+	case *json.Integer:
+		return new(big.Rat).SetInt64(a.Value)
+	case *json.Number:
+		return floatRat(a.Value)
+	case *Decimal:
+		if a.rat != nil {
+			return a.rat
+		}
+		if r, ok := new(big.Rat).SetString(a.Text); ok {
+			return r
+		}
+		return floatRat(a.Value)
+	}
+	return new(big.Rat)
+}
+
+// floatRat returns f as the shortest decimal that gives the same float64,
+// which is the decimal written in the JSON if Parse kept it as *json.Number.
+func floatRat(f float64) *big.Rat {
+	if r, ok := new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64)); ok {
+		return r
+	}
+	// Infinities and NaN are not valid in JSON.
+	return new(big.Rat)
+}
+
+// numberParam returns the number as int64, float64 or, for *Decimal, as
+// encoding/json.Number for ValidationError.Params.
+func numberParam(a json.Value) interface{} {
+	switch a := a.(type) {
+	case *json.Integer:
+		return a.Value
+	case *json.Number:
+		return a.Value
+	case *Decimal:
+		return stdjson.Number(a.Text)
+	}
+	return nil
+}
+
+func formatNumber(a json.Value) string {
+	switch a := a.(type) {
+	case *json.Integer:
+		return strconv.FormatInt(a.Value, 10)
+	case *json.Number:
+		return strconv.FormatFloat(a.Value, 'g', -1, 64)
+	case *Decimal:
+		return a.Text
+	}
+	return fmt.Sprint(a)
+}
@@ -0,0 +1,1086 @@
+package schema
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	json "github.com/cesanta/ucl"
+)
+
+// node is a compiled schema. Nodes form a graph that mirrors the schema, with
+// "$ref"s replaced by pointers to the nodes they refer to, so recursive schemas
+// produce cycles.
+type node struct {
+	// path is the location of the schema, e.g. "#/properties/foo" or
+	// "http://example.com/schema.json#/definitions/bar".
+	path    string
+	dialect *Dialect
+
+	// ref is the target of "$ref". Before 2019-09 the other keywords next to
+	// it are ignored, so they are not compiled.
+	ref *node
+	// recursiveRef is the target of "$recursiveRef" before looking at the
+	// dynamic scope, see state.resolveRecursiveRef.
+	recursiveRef    *node
+	recursiveAnchor bool
+	// dynamicRef is the target of "$dynamicRef" before looking at the dynamic
+	// scope. dynamicRefAnchor is the anchor name to look for there, "" if the
+	// target has no "$dynamicAnchor" with the same name, see
+	// state.resolveDynamicRef.
+	dynamicRef       *node
+	dynamicRefAnchor string
+	// resource is set if n is the root of a schema resource: a document or a
+	// schema with an id.
+	resource bool
+	// dynamicAnchors are the schemas in the resource with "$dynamicAnchor", by
+	// the anchor name. Only set if resource is set.
+	dynamicAnchors map[string]*node
+
+	types []string
+	allOf []*node
+	anyOf []*node
+	oneOf []*node
+	not   *node
+	enum  *json.Array
+	// "if", "then" and "else". Either of them can be nil.
+	ifSchema   *node
+	thenSchema *node
+	elseSchema *node
+	// constant is the value of "const", nil if not set.
+	constant json.Value
+
+	// Strings.
+	minLength int // -1 if not set, same for other limits.
+	maxLength int
+	pattern   Regexp
+	format    string
+	// formatCheck checks the format, nil if it is not known or is only an
+	// annotation.
+	formatCheck func(val string) error
+
+	// Arrays.
+	items           *node   // "items" with a single schema.
+	itemsList       []*node // "items" with an array of schemas or "prefixItems".
+	additionalItems *node   // "additionalItems", or "items" next to "prefixItems".
+	minItems        int
+	maxItems        int
+	uniqueItems     bool
+	contains        *node
+	minContains     int
+	maxContains     int
+	// unevaluatedItems applies to the items not evaluated by any of
+	// "items", "additionalItems" or "unevaluatedItems" of n itself or of the
+	// subschemas applied in place, like "allOf".
+	unevaluatedItems *node
+
+	// Objects.
+	minProperties        int
+	maxProperties        int
+	required             []string
+	properties           map[string]*node
+	patternProperties    []patternProperty
+	additionalProperties *node
+	dependencies         []dependency
+	propertyNames        *node
+	// unevaluatedProperties is like unevaluatedItems, but for properties.
+	unevaluatedProperties *node
+
+	// Numbers. These are *json.Integer, *json.Number or *Decimal. In draft 4
+	// "maximum" with "exclusiveMaximum": true is stored as exclusiveMaximum,
+	// same for the minimum.
+	multipleOf       json.Value
+	maximum          json.Value
+	exclusiveMaximum json.Value
+	minimum          json.Value
+	exclusiveMinimum json.Value
+
+	// annotations are the annotation keywords of the schema, like "title",
+	// sorted by keyword.
+	annotations []annotation
+	// keywords is the number of properties of the schema object, see
+	// Limits.MaxEvaluations.
+	keywords int
+	// custom are the custom keywords of the schema, sorted by name.
+	custom []customKeyword
+
+	// never is set for the false schema and for "false" in "additionalItems"
+	// and "additionalProperties": no value is valid against such node.
+	never bool
+}
+
+// inPlace returns subschemas that apply to the same value as n itself.
+func (n *node) inPlace() []*node {
+	r := []*node{}
+	if n.ref != nil {
+		r = append(r, n.ref)
+	}
+	if n.recursiveRef != nil {
+		r = append(r, n.recursiveRef)
+	}
+	if n.dynamicRef != nil {
+		r = append(r, n.dynamicRef)
+	}
+	r = append(r, n.allOf...)
+	r = append(r, n.anyOf...)
+	r = append(r, n.oneOf...)
+	if n.not != nil {
+		r = append(r, n.not)
+	}
+	for _, s := range []*node{n.ifSchema, n.thenSchema, n.elseSchema} {
+		if s != nil {
+			r = append(r, s)
+		}
+	}
+	for _, d := range n.dependencies {
+		if d.schema != nil {
+			r = append(r, d.schema)
+		}
+	}
+	return r
+}
+
+// nested returns subschemas that apply to items or properties of the value.
+func (n *node) nested() []*node {
+	r := []*node{}
+	if n.items != nil {
+		r = append(r, n.items)
+	}
+	r = append(r, n.itemsList...)
+	if n.additionalItems != nil {
+		r = append(r, n.additionalItems)
+	}
+	if n.contains != nil {
+		r = append(r, n.contains)
+	}
+	if n.unevaluatedItems != nil {
+		r = append(r, n.unevaluatedItems)
+	}
+	for _, p := range n.properties {
+		r = append(r, p)
+	}
+	for _, pp := range n.patternProperties {
+		r = append(r, pp.schema)
+	}
+	if n.additionalProperties != nil {
+		r = append(r, n.additionalProperties)
+	}
+	if n.propertyNames != nil {
+		r = append(r, n.propertyNames)
+	}
+	if n.unevaluatedProperties != nil {
+		r = append(r, n.unevaluatedProperties)
+	}
+	return r
+}
+
+type patternProperty struct {
+	re     Regexp
+	schema *node
+}
+
+type dependency struct {
+	keyword  string // "dependencies", "dependentRequired" or "dependentSchemas".
+	property string
+	required []string // Set if the dependency is an array of property names.
+	schema   *node    // Set if the dependency is a schema.
+}
+
+// location describes where a schema comes from.
+type location struct {
+	doc     string // URI of the document, "" for the root schema.
+	pointer string // JSON Pointer from the root of the document.
+	base    string // Base URI used to resolve "id" and "$ref".
+	dialect *Dialect
+}
+
+func (l location) String() string {
+	return l.doc + "#" + l.pointer
+}
+
+func (l location) child(tokens ...string) location {
+	for _, t := range tokens {
+		l.pointer += "/" + escapeRefToken(t)
+	}
+	return l
+}
+
+// resource is a schema that can be referred to by URI.
+type resource struct {
+	schema json.Value
+	loc    location
+}
+
+// compiler turns schemas into graphs of nodes.
+type compiler struct {
+	loader *Loader
+	nodes  map[json.Value]*node
+	// resources holds schemas with an id by their absolute URI, and the root
+	// schemas of documents by the URI of the document.
+	resources map[string]resource
+	// bases holds the base URI for every schema seen by walk.
+	bases map[json.Value]string
+	// dynamicAnchors holds schemas with "$dynamicAnchor" by the base URI of
+	// their resource and the anchor name.
+	dynamicAnchors map[string]map[string]resource
+	// unevaluated is set if any of the compiled schemas has
+	// "unevaluatedItems" or "unevaluatedProperties", which means that
+	// validation needs to keep track of evaluated items and properties.
+	unevaluated bool
+	// keywords are the custom keywords by name.
+	keywords map[string]Keyword
+	// formats are the custom formats, which take precedence over the built-in
+	// ones. unknownFormat is called for formats that are not known, if set.
+	formats       map[string]func(string) error
+	unknownFormat func(path string, format string) error
+	// assertFormats is set if "format" is checked even in the dialects where
+	// it is only an annotation.
+	assertFormats bool
+	// regexps compiles "pattern", "patternProperties" and "format": "regex".
+	regexps RegexpEngine
+}
+
+func newCompiler(loader *Loader, keywords map[string]Keyword) *compiler {
+	return &compiler{
+		loader:         loader,
+		keywords:       keywords,
+		regexps:        RE2,
+		nodes:          map[json.Value]*node{},
+		resources:      map[string]resource{},
+		bases:          map[json.Value]string{},
+		dynamicAnchors: map[string]map[string]resource{},
+	}
+}
+
+// compileRoot compiles the root schema, which must already be validated
+// against d.
+func (c *compiler) compileRoot(schema json.Value, d *Dialect) (*node, error) {
+	loc := location{dialect: d}
+	c.walk(schema, loc)
+	if _, found := c.resources[""]; !found {
+		c.resources[""] = resource{schema: schema, loc: loc}
+	}
+	root, err := c.compile(schema, loc)
+	if err != nil {
+		return nil, err
+	}
+	if cycle := findCycle(root); cycle != nil {
+		return nil, fmt.Errorf("schema refers to itself without descending into the value, which would never terminate: %s", strings.Join(cycle, " -> "))
+	}
+	return root, nil
+}
+
+// findCycle looks for a chain of subschemas that apply to the same value and
+// lead back to where it started, like {"$ref": "#"}. It returns paths of the
+// schemas in the cycle or nil if there is none. Recursion through "items" or
+// "properties" is fine, since every step there goes deeper into the value.
+func findCycle(root *node) []string {
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+	state := map[*node]int{}
+	stack := []*node{}
+	var visit func(n *node) []string
+	visit = func(n *node) []string {
+		switch state[n] {
+		case done:
+			return nil
+		case inProgress:
+			cycle := []string{}
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i] == n {
+					for _, s := range stack[i:] {
+						cycle = append(cycle, s.path)
+					}
+					return append(cycle, n.path)
+				}
+			}
+		}
+		state[n] = inProgress
+		stack = append(stack, n)
+		for _, sub := range n.inPlace() {
+			if cycle := visit(sub); cycle != nil {
+				return cycle
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[n] = done
+		return nil
+	}
+
+	// Every node is checked as a starting point for a cycle, including the
+	// ones nested in "items" and "properties".
+	seen := map[*node]bool{}
+	queue := []*node{root}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+		if cycle := visit(n); cycle != nil {
+			return cycle
+		}
+		queue = append(queue, n.inPlace()...)
+		queue = append(queue, n.nested()...)
+	}
+	return nil
+}
+
+// walk finds all schemas with an id in v and registers them as resources. It
+// also adds them to the loader, so other validators sharing the loader can
+// refer to them.
+func (c *compiler) walk(v json.Value, loc location) {
+	// HERE BE DRAGONS
+	// I couldn't make much sense out of the part of the spec about scopes, so
+	// this might behave in a weird way.
+	obj, ok := v.(*json.Object)
+	if !ok {
+		return
+	}
+	if e := embeddedDialect(obj); e != nil && loc.pointer != "" {
+		loc.dialect = e
+	}
+	if _, found := obj.Lookup("$ref"); found && !loc.dialect.refSiblings {
+		c.bases[v] = loc.base
+		return
+	}
+	if id, ok := obj.Find(loc.dialect.idKeyword).(*json.String); ok {
+		if u := resolveURI(loc.base, id.Value); u != "" {
+			c.resources[u] = resource{schema: v, loc: location{doc: loc.doc, pointer: loc.pointer, base: u, dialect: loc.dialect}}
+			if !strings.Contains(u, "#") {
+				c.loader.AddAs(v, u)
+			}
+			loc.base = u
+		}
+	}
+	if loc.dialect.anchorKeyword != "" {
+		if anchor, ok := obj.Find(loc.dialect.anchorKeyword).(*json.String); ok {
+			if u := resolveURI(loc.base, "#"+anchor.Value); u != "" {
+				c.resources[u] = resource{schema: v, loc: loc}
+			}
+		}
+	}
+	if loc.dialect.knows("$dynamicAnchor") {
+		// Dynamic anchors also work as plain anchors for "$ref".
+		if anchor, ok := obj.Find("$dynamicAnchor").(*json.String); ok {
+			if u := resolveURI(loc.base, "#"+anchor.Value); u != "" {
+				c.resources[u] = resource{schema: v, loc: loc}
+			}
+			if c.dynamicAnchors[loc.base] == nil {
+				c.dynamicAnchors[loc.base] = map[string]resource{}
+			}
+			c.dynamicAnchors[loc.base][anchor.Value] = resource{schema: v, loc: loc}
+		}
+	}
+	c.bases[v] = loc.base
+	for _, kw := range loc.dialect.subschemaKeywords {
+		switch s := obj.Find(kw).(type) {
+		case *json.Object:
+			c.walk(s, loc.child(kw))
+		case *json.Array:
+			for i, item := range s.Value {
+				c.walk(item, loc.child(kw, fmt.Sprint(i)))
+			}
+		}
+	}
+	for _, kw := range loc.dialect.subschemaMapKeywords {
+		if m, ok := obj.Find(kw).(*json.Object); ok {
+			for k, s := range m.Value {
+				c.walk(s, loc.child(kw, k.Value))
+			}
+		}
+	}
+}
+
+// resolveURI resolves ref against base. It returns ref as is if base is empty
+// or either of them does not parse, and "" if the result is empty.
+func resolveURI(base string, ref string) string {
+	r, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	if base != "" && !r.IsAbs() {
+		b, err := url.Parse(base)
+		if err != nil {
+			return ref
+		}
+		r = b.ResolveReference(r)
+	}
+	return r.String()
+}
+
+// resolve returns the schema referred to by ref, which is relative to the
+// base of from. Documents fetched from the loader are expected to be written
+// for the same dialect as the referring schema unless they set "$schema".
+func (c *compiler) resolve(from location, ref string) (json.Value, location, error) {
+	uri := resolveURI(from.base, ref)
+	if r, found := c.resources[uri]; found {
+		return r.schema, r.loc, nil
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, location{}, fmt.Errorf("failed to parse %q: %s", uri, err)
+	}
+	fragment := u.Fragment
+	u.Fragment = ""
+	doc := u.String()
+	r, found := c.resources[doc]
+	if !found {
+		s, err := c.loader.Get(doc)
+		if err != nil {
+			return nil, location{}, err
+		}
+		d := DetectDialect(s)
+		if d == nil {
+			d = from.dialect
+		}
+		if err := d.validateSchema("#", s); err != nil {
+			return nil, location{}, fmt.Errorf("%q is not a valid %s schema: %s", doc, d, err)
+		}
+		r = resource{schema: s, loc: location{doc: doc, base: doc, dialect: d}}
+		c.resources[doc] = r
+		c.walk(s, r.loc)
+	}
+	s, err := resolveRef(r.schema, fragment)
+	if err != nil {
+		return nil, location{}, fmt.Errorf("failed to resolve ref %q: %s", uri, err)
+	}
+	loc := r.loc
+	loc.pointer += fragment
+	return s, loc, nil
+}
+
+// newNode returns a node for the schema at loc with no keywords set.
+func newNode(loc location) *node {
+	return &node{
+		path:          loc.String(),
+		dialect:       loc.dialect,
+		minLength:     -1,
+		maxLength:     -1,
+		minItems:      -1,
+		maxItems:      -1,
+		minProperties: -1,
+		maxProperties: -1,
+	}
+}
+
+func (c *compiler) compile(v json.Value, loc location) (*node, error) {
+	if n, found := c.nodes[v]; found {
+		return n, nil
+	}
+	if b, ok := v.(*json.Bool); ok && loc.dialect.booleanSchemas {
+		// true is the same as {}, and false is the same as {"not": {}}.
+		n := &node{path: loc.String(), dialect: loc.dialect, never: !b.Value}
+		if b.Value {
+			n = newNode(loc)
+		}
+		c.nodes[v] = n
+		return n, nil
+	}
+	schema, ok := v.(*json.Object)
+	if !ok {
+		if loc.dialect.booleanSchemas {
+			return nil, fmt.Errorf("%q: schema must be an object or a boolean", loc)
+		}
+		return nil, fmt.Errorf("%q: schema must be an object", loc)
+	}
+	if base, found := c.bases[v]; found {
+		loc.base = base
+	}
+	if e := embeddedDialect(schema); e != nil && loc.pointer != "" {
+		loc.dialect = e
+	}
+	// Keywords from vocabularies not used by a custom meta-schema are ignored.
+	// Custom keywords are not part of any vocabulary, so they always apply.
+	raw := schema
+	schema = loc.dialect.filterKeywords(schema)
+	n := newNode(loc)
+	n.keywords = len(schema.Value)
+	// Node is added to the cache before compiling subschemas to make recursive
+	// references work.
+	c.nodes[v] = n
+
+	if ref, found := schema.Lookup("$ref"); found {
+		sref, ok := ref.(*json.String)
+		if !ok {
+			return nil, fmt.Errorf("%q must be a string", n.path+"/$ref")
+		}
+		s, sloc, err := c.resolve(loc, sref.Value)
+		if err != nil {
+			return nil, err
+		}
+		n.ref, err = c.compile(s, sloc)
+		if err != nil || !loc.dialect.refSiblings {
+			return n, err
+		}
+	}
+	if _, found := schema.Lookup(loc.dialect.idKeyword); found || loc.pointer == "" {
+		n.resource = true
+	}
+
+	var err error
+	if loc.dialect.knows("$recursiveRef") {
+		if x, found := schema.Lookup("$recursiveRef"); found {
+			ref, ok := x.(*json.String)
+			if !ok {
+				return nil, fmt.Errorf("%q must be a string", n.path+"/$recursiveRef")
+			}
+			s, sloc, err := c.resolve(loc, ref.Value)
+			if err != nil {
+				return nil, err
+			}
+			if n.recursiveRef, err = c.compile(s, sloc); err != nil {
+				return nil, err
+			}
+		}
+		if x, found := schema.Lookup("$recursiveAnchor"); found {
+			anchor, ok := x.(*json.Bool)
+			if !ok {
+				return nil, fmt.Errorf("%q must be a boolean", n.path+"/$recursiveAnchor")
+			}
+			n.recursiveAnchor = anchor.Value
+		}
+	}
+	if loc.dialect.knows("$dynamicRef") {
+		if err := c.compileDynamicRef(n, schema, loc); err != nil {
+			return nil, err
+		}
+	}
+	if n.types, err = compileType(n.path, schema); err != nil {
+		return nil, err
+	}
+	if n.allOf, err = c.compileSchemaArray(schema, loc, "allOf"); err != nil {
+		return nil, err
+	}
+	if n.anyOf, err = c.compileSchemaArray(schema, loc, "anyOf"); err != nil {
+		return nil, err
+	}
+	if n.oneOf, err = c.compileSchemaArray(schema, loc, "oneOf"); err != nil {
+		return nil, err
+	}
+	if not, found := schema.Lookup("not"); found {
+		if n.not, err = c.compile(not, loc.child("not")); err != nil {
+			return nil, err
+		}
+	}
+	if x, found := schema.Lookup("enum"); found {
+		enum, ok := x.(*json.Array)
+		if !ok {
+			return nil, fmt.Errorf("%q must be an array", n.path+"/enum")
+		}
+		if len(enum.Value) < 1 && !loc.dialect.emptyArrays {
+			return nil, fmt.Errorf("%q must have at least one element", n.path+"/enum")
+		}
+		n.enum = enum
+	}
+	if x, found := schema.Lookup("const"); found && loc.dialect.knows("const") {
+		n.constant = x
+	}
+	if loc.dialect.knows("if") {
+		for _, s := range []struct {
+			keyword string
+			node    **node
+		}{
+			{"if", &n.ifSchema},
+			{"then", &n.thenSchema},
+			{"else", &n.elseSchema},
+		} {
+			if x, found := schema.Lookup(s.keyword); found {
+				if *s.node, err = c.compile(x, loc.child(s.keyword)); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if err := c.compileString(n, schema); err != nil {
+		return nil, err
+	}
+	if err := c.compileArray(n, schema, loc); err != nil {
+		return nil, err
+	}
+	if err := c.compileObject(n, schema, loc); err != nil {
+		return nil, err
+	}
+	if err := c.compileNumber(n, schema); err != nil {
+		return nil, err
+	}
+	n.annotations = compileAnnotations(schema, c.keywords)
+	if err := c.compileCustom(n, raw); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// compileDynamicRef compiles "$dynamicRef" and, if n is the root of a
+// resource, the schemas with "$dynamicAnchor" in it.
+func (c *compiler) compileDynamicRef(n *node, schema *json.Object, loc location) error {
+	if n.resource {
+		for name, r := range c.dynamicAnchors[loc.base] {
+			s, err := c.compile(r.schema, r.loc)
+			if err != nil {
+				return err
+			}
+			if n.dynamicAnchors == nil {
+				n.dynamicAnchors = map[string]*node{}
+			}
+			n.dynamicAnchors[name] = s
+		}
+	}
+	x, found := schema.Lookup("$dynamicRef")
+	if !found {
+		return nil
+	}
+	ref, ok := x.(*json.String)
+	if !ok {
+		return fmt.Errorf("%q must be a string", n.path+"/$dynamicRef")
+	}
+	s, sloc, err := c.resolve(loc, ref.Value)
+	if err != nil {
+		return err
+	}
+	if n.dynamicRef, err = c.compile(s, sloc); err != nil {
+		return err
+	}
+	// The dynamic scope is only used if the initial target has a dynamic
+	// anchor with the name from the fragment, otherwise it's a plain "$ref".
+	if i := strings.Index(ref.Value, "#"); i >= 0 {
+		name := ref.Value[i+1:]
+		if obj, ok := s.(*json.Object); ok {
+			if anchor, ok := obj.Find("$dynamicAnchor").(*json.String); ok && anchor.Value == name {
+				n.dynamicRefAnchor = name
+			}
+		}
+	}
+	return nil
+}
+
+// compileBoolOrSchema compiles keywords like "additionalProperties" that can
+// be either a boolean or a schema. In draft 4 it returns nil for true, as it's
+// the same as if the keyword was not present at all. Later drafts have
+// boolean schemas, and true still marks the items or properties as evaluated.
+func (c *compiler) compileBoolOrSchema(v json.Value, loc location) (*node, error) {
+	switch v := v.(type) {
+	case *json.Bool:
+		if loc.dialect.booleanSchemas {
+			return c.compile(v, loc)
+		}
+		if v.Value {
+			return nil, nil
+		}
+		return &node{path: loc.String(), dialect: loc.dialect, never: true}, nil
+	case *json.Object:
+		return c.compile(v, loc)
+	default:
+		return nil, fmt.Errorf("%q must be an object or a boolean", loc)
+	}
+}
+
+func compileType(path string, schema *json.Object) ([]string, error) {
+	t, found := schema.Lookup("type")
+	if !found {
+		return nil, nil
+	}
+	switch t := t.(type) {
+	case *json.String:
+		return []string{t.Value}, nil
+	case *json.Array:
+		types := []string{}
+		for i, v := range t.Value {
+			s, ok := v.(*json.String)
+			if !ok {
+				return nil, fmt.Errorf("%q: must be a string", fmt.Sprintf("%s/type/%d", path, i))
+			}
+			types = append(types, s.Value)
+		}
+		return types, nil
+	default:
+		return nil, fmt.Errorf("%q: must be a string or an array", path+"/type")
+	}
+}
+
+func (c *compiler) compileSchemaArray(schema *json.Object, loc location, keyword string) ([]*node, error) {
+	x, found := schema.Lookup(keyword)
+	if !found {
+		return nil, nil
+	}
+	a, ok := x.(*json.Array)
+	if !ok {
+		return nil, fmt.Errorf("%q must be an array", loc.child(keyword))
+	}
+	if len(a.Value) < 1 {
+		return nil, fmt.Errorf("%q must have at least 1 element", loc.child(keyword))
+	}
+	r := make([]*node, len(a.Value))
+	for i, s := range a.Value {
+		n, err := c.compile(s, loc.child(keyword, fmt.Sprint(i)))
+		if err != nil {
+			return nil, err
+		}
+		r[i] = n
+	}
+	return r, nil
+}
+
+// compileLimit returns the value of a non-negative integer keyword, or -1 if
+// it is not present.
+func compileLimit(path string, schema *json.Object, keyword string) (int, error) {
+	x, found := schema.Lookup(keyword)
+	if !found {
+		return -1, nil
+	}
+	n, ok := x.(*json.Integer)
+	if !ok || n.Value < 0 {
+		return 0, fmt.Errorf("%q must be a non-negative integer", path+"/"+keyword)
+	}
+	return int(n.Value), nil
+}
+
+func (c *compiler) compileString(n *node, schema *json.Object) error {
+	var err error
+	if n.minLength, err = compileLimit(n.path, schema, "minLength"); err != nil {
+		return err
+	}
+	if n.maxLength, err = compileLimit(n.path, schema, "maxLength"); err != nil {
+		return err
+	}
+	if x, found := schema.Lookup("pattern"); found {
+		pattern, ok := x.(*json.String)
+		if !ok {
+			return fmt.Errorf("%q must be a string", n.path+"/pattern")
+		}
+		if n.pattern, err = c.regexps.Compile(pattern.Value); err != nil {
+			return fmt.Errorf("%q must be a valid regexp: %s", n.path+"/pattern", err)
+		}
+	}
+	if x, found := schema.Lookup("format"); found {
+		format, ok := x.(*json.String)
+		if !ok {
+			return fmt.Errorf("%q must be a string", n.path+"/format")
+		}
+		n.format = format.Value
+		if n.formatCheck = c.formats[n.format]; n.formatCheck == nil {
+			n.formatCheck = formats[n.format]
+		}
+		if n.format == "regex" && c.formats[n.format] == nil {
+			n.formatCheck = c.checkRegexp
+		}
+		if n.formatCheck == nil && c.unknownFormat != nil {
+			if err := c.unknownFormat(n.path+"/format", n.format); err != nil {
+				return err
+			}
+		}
+		if n.dialect.formatAnnotation && !c.assertFormats {
+			n.formatCheck = nil
+		}
+	}
+	return nil
+}
+
+func (c *compiler) compileArray(n *node, schema *json.Object, loc location) error {
+	var err error
+	if loc.dialect.prefixItems {
+		if _, found := schema.Lookup("prefixItems"); found {
+			if n.itemsList, err = c.compileSchemaArray(schema, loc, "prefixItems"); err != nil {
+				return err
+			}
+		}
+		if x, found := schema.Lookup("items"); found {
+			// Without "prefixItems" it's the same as "items" in earlier
+			// drafts, otherwise it's the same as "additionalItems".
+			target := &n.items
+			if n.itemsList != nil {
+				target = &n.additionalItems
+			}
+			if *target, err = c.compile(x, loc.child("items")); err != nil {
+				return err
+			}
+		}
+	} else if x, found := schema.Lookup("items"); found {
+		// If "items" is not present it is assumed to be an empty object, which
+		// means that any item is valid and "additionalItems" is ignored.
+		switch items := x.(type) {
+		case *json.Object, *json.Bool:
+			if n.items, err = c.compile(items, loc.child("items")); err != nil {
+				return err
+			}
+		case *json.Array:
+			if n.itemsList, err = c.compileSchemaArray(schema, loc, "items"); err != nil {
+				return err
+			}
+			if ai, found := schema.Lookup("additionalItems"); found {
+				if n.additionalItems, err = c.compileBoolOrSchema(ai, loc.child("additionalItems")); err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("%q must be an array or an object", n.path+"/items")
+		}
+	}
+	if n.minItems, err = compileLimit(n.path, schema, "minItems"); err != nil {
+		return err
+	}
+	if n.maxItems, err = compileLimit(n.path, schema, "maxItems"); err != nil {
+		return err
+	}
+	if x, found := schema.Lookup("uniqueItems"); found {
+		u, ok := x.(*json.Bool)
+		if !ok {
+			return fmt.Errorf("%q must be a boolean", n.path+"/uniqueItems")
+		}
+		n.uniqueItems = u.Value
+	}
+	if x, found := schema.Lookup("contains"); found && loc.dialect.knows("contains") {
+		if n.contains, err = c.compile(x, loc.child("contains")); err != nil {
+			return err
+		}
+	}
+	n.minContains, n.maxContains = -1, -1
+	if loc.dialect.knows("minContains") {
+		if n.minContains, err = compileLimit(n.path, schema, "minContains"); err != nil {
+			return err
+		}
+		if n.maxContains, err = compileLimit(n.path, schema, "maxContains"); err != nil {
+			return err
+		}
+	}
+	if x, found := schema.Lookup("unevaluatedItems"); found && loc.dialect.knows("unevaluatedItems") {
+		if n.unevaluatedItems, err = c.compile(x, loc.child("unevaluatedItems")); err != nil {
+			return err
+		}
+		c.unevaluated = true
+	}
+	return nil
+}
+
+func (c *compiler) compileObject(n *node, schema *json.Object, loc location) error {
+	var err error
+	if n.minProperties, err = compileLimit(n.path, schema, "minProperties"); err != nil {
+		return err
+	}
+	if n.maxProperties, err = compileLimit(n.path, schema, "maxProperties"); err != nil {
+		return err
+	}
+	if x, found := schema.Lookup("required"); found {
+		req, ok := x.(*json.Array)
+		if !ok {
+			return fmt.Errorf("%q must be an array", n.path+"/required")
+		}
+		if len(req.Value) < 1 && !loc.dialect.emptyArrays {
+			return fmt.Errorf("%q must be an array with at least one element", n.path+"/required")
+		}
+		for i, p := range req.Value {
+			prop, ok := p.(*json.String)
+			if !ok {
+				return fmt.Errorf("%q must be a string", fmt.Sprintf("%s/required/%d", n.path, i))
+			}
+			n.required = append(n.required, prop.Value)
+		}
+	}
+	if x, found := schema.Lookup("properties"); found {
+		props, ok := x.(*json.Object)
+		if !ok {
+			return fmt.Errorf("%q must be an object", n.path+"/properties")
+		}
+		n.properties = map[string]*node{}
+		for k, v := range props.Value {
+			if n.properties[k.Value], err = c.compile(v, loc.child("properties", k.Value)); err != nil {
+				return err
+			}
+		}
+	}
+	if x, found := schema.Lookup("patternProperties"); found {
+		pprops, ok := x.(*json.Object)
+		if !ok {
+			return fmt.Errorf("%q must be an object", n.path+"/patternProperties")
+		}
+		for _, k := range sortedKeys(pprops) {
+			re, err := c.regexps.Compile(k)
+			if err != nil {
+				return fmt.Errorf("%q: %q is not a valid regexp: %s", n.path+"/patternProperties", k, err)
+			}
+			s, err := c.compile(pprops.Find(k), loc.child("patternProperties", k))
+			if err != nil {
+				return err
+			}
+			n.patternProperties = append(n.patternProperties, patternProperty{re: re, schema: s})
+		}
+	}
+	if x, found := schema.Lookup("additionalProperties"); found {
+		if n.additionalProperties, err = c.compileBoolOrSchema(x, loc.child("additionalProperties")); err != nil {
+			return err
+		}
+	}
+	if x, found := schema.Lookup("dependencies"); found && loc.dialect.knows("dependencies") {
+		deps, ok := x.(*json.Object)
+		if !ok {
+			return fmt.Errorf("%q must be an object", n.path+"/dependencies")
+		}
+		for _, prop := range sortedKeys(deps) {
+			dloc := loc.child("dependencies", prop)
+			d := dependency{keyword: "dependencies", property: prop}
+			switch deps := deps.Find(prop).(type) {
+			case *json.Array:
+				if len(deps.Value) < 1 && !loc.dialect.emptyArrays {
+					return fmt.Errorf("%q must have at least one element", dloc)
+				}
+				for i, item := range deps.Value {
+					req, ok := item.(*json.String)
+					if !ok {
+						return fmt.Errorf("%q must be a string", dloc.child(fmt.Sprint(i)))
+					}
+					d.required = append(d.required, req.Value)
+				}
+			case *json.Object, *json.Bool:
+				if d.schema, err = c.compile(deps, dloc); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("%q must be an array or an object", dloc)
+			}
+			n.dependencies = append(n.dependencies, d)
+		}
+	}
+	if x, found := schema.Lookup("propertyNames"); found && loc.dialect.knows("propertyNames") {
+		if n.propertyNames, err = c.compile(x, loc.child("propertyNames")); err != nil {
+			return err
+		}
+	}
+	if loc.dialect.knows("dependentRequired") {
+		if err := c.compileDependentRequired(n, schema, loc); err != nil {
+			return err
+		}
+		if err := c.compileDependentSchemas(n, schema, loc); err != nil {
+			return err
+		}
+	}
+	if x, found := schema.Lookup("unevaluatedProperties"); found && loc.dialect.knows("unevaluatedProperties") {
+		if n.unevaluatedProperties, err = c.compile(x, loc.child("unevaluatedProperties")); err != nil {
+			return err
+		}
+		c.unevaluated = true
+	}
+	return nil
+}
+
+func (c *compiler) compileDependentRequired(n *node, schema *json.Object, loc location) error {
+	x, found := schema.Lookup("dependentRequired")
+	if !found {
+		return nil
+	}
+	deps, ok := x.(*json.Object)
+	if !ok {
+		return fmt.Errorf("%q must be an object", n.path+"/dependentRequired")
+	}
+	for _, prop := range sortedKeys(deps) {
+		dloc := loc.child("dependentRequired", prop)
+		a, ok := deps.Find(prop).(*json.Array)
+		if !ok {
+			return fmt.Errorf("%q must be an array", dloc)
+		}
+		d := dependency{keyword: "dependentRequired", property: prop}
+		for i, item := range a.Value {
+			req, ok := item.(*json.String)
+			if !ok {
+				return fmt.Errorf("%q must be a string", dloc.child(fmt.Sprint(i)))
+			}
+			d.required = append(d.required, req.Value)
+		}
+		n.dependencies = append(n.dependencies, d)
+	}
+	return nil
+}
+
+func (c *compiler) compileDependentSchemas(n *node, schema *json.Object, loc location) error {
+	x, found := schema.Lookup("dependentSchemas")
+	if !found {
+		return nil
+	}
+	deps, ok := x.(*json.Object)
+	if !ok {
+		return fmt.Errorf("%q must be an object", n.path+"/dependentSchemas")
+	}
+	for _, prop := range sortedKeys(deps) {
+		s, err := c.compile(deps.Find(prop), loc.child("dependentSchemas", prop))
+		if err != nil {
+			return err
+		}
+		n.dependencies = append(n.dependencies, dependency{keyword: "dependentSchemas", property: prop, schema: s})
+	}
+	return nil
+}
+
+func (c *compiler) compileNumber(n *node, schema *json.Object) error {
+	number := func(keyword string) (json.Value, error) {
+		x, found := schema.Lookup(keyword)
+		if !found {
+			return nil, nil
+		}
+		switch x.(type) {
+		case *json.Number, *json.Integer, *Decimal:
+			return x, nil
+		}
+		return nil, fmt.Errorf("%q must be a number", n.path+"/"+keyword)
+	}
+	var err error
+	if n.multipleOf, err = number("multipleOf"); err != nil {
+		return err
+	}
+	if n.multipleOf != nil && sign(n.multipleOf) <= 0 {
+		return fmt.Errorf("%q must be a number and greater than 0", n.path+"/multipleOf")
+	}
+	if n.maximum, err = number("maximum"); err != nil {
+		return err
+	}
+	if n.minimum, err = number("minimum"); err != nil {
+		return err
+	}
+	if !n.dialect.booleanExclusiveLimits {
+		if n.exclusiveMaximum, err = number("exclusiveMaximum"); err != nil {
+			return err
+		}
+		if n.exclusiveMinimum, err = number("exclusiveMinimum"); err != nil {
+			return err
+		}
+		return nil
+	}
+	boolean := func(keyword string) (bool, error) {
+		x, found := schema.Lookup(keyword)
+		if !found {
+			return false, nil
+		}
+		b, ok := x.(*json.Bool)
+		if !ok {
+			return false, fmt.Errorf("%q must be a boolean", n.path+"/"+keyword)
+		}
+		return b.Value, nil
+	}
+	exclusive, err := boolean("exclusiveMaximum")
+	if err != nil {
+		return err
+	}
+	if exclusive {
+		n.maximum, n.exclusiveMaximum = nil, n.maximum
+	}
+	if exclusive, err = boolean("exclusiveMinimum"); err != nil {
+		return err
+	}
+	if exclusive {
+		n.minimum, n.exclusiveMinimum = nil, n.minimum
+	}
+	return nil
+}
+
+func sortedKeys(o *json.Object) []string {
+	r := make([]string, 0, len(o.Value))
+	for k := range o.Value {
+		r = append(r, k.Value)
+	}
+	sort.Strings(r)
+	return r
+}
@@ -0,0 +1,150 @@
+package schema
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"strconv"
+
+	json "github.com/cesanta/ucl"
+)
+
+// Decimal is a number that neither *json.Integer nor *json.Number can hold
+// exactly, like 18446744073709551617 or 0.10000000000000000001, kept as the
+// decimal it is written as. Parse returns it for such numbers, and validation
+// compares it exactly. The embedded json.Number is the nearest float64, for
+// code that only knows about the values of the ucl package.
+type Decimal struct {
+	json.Number
+	// Text is the number as it is written in JSON.
+	Text string
+
+	// rat is Text as a fraction, set by Parse. It is never modified.
+	rat *big.Rat
+}
+
+func (d *Decimal) String() string {
+	return d.Text
+}
+
+// Parse is like json.Parse, except that numbers of JSON input are kept exact,
+// see Decimal. Anything else json.Parse accepts, and JSON with numbers too
+// large even for Decimal, is parsed by json.Parse, so Parse accepts the same
+// input.
+func Parse(r io.Reader) (json.Value, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if val, err := parseJSON(data); err == nil {
+		return val, nil
+	}
+	return json.Parse(bytes.NewReader(data))
+}
+
+// parseJSON parses data as strict JSON with exact numbers.
+func parseJSON(data []byte) (json.Value, error) {
+	d := stdjson.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	tok, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+	val, err := parseValue(d, tok, 0)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := d.Token(); err != io.EOF {
+		if err == nil {
+			err = errors.New("unexpected data after the value")
+		}
+		return nil, err
+	}
+	return val, nil
+}
+
+// maxNesting is how deeply arrays and objects can be nested in the values
+// read with encoding/json, same as it allows itself.
+const maxNesting = 10000
+
+// parseValue returns the value that starts with tok, at depth, reading the
+// rest of it from d if it is an array or an object.
+func parseValue(d *stdjson.Decoder, tok stdjson.Token, depth int) (json.Value, error) {
+	switch tok := tok.(type) {
+	case stdjson.Delim:
+		if depth >= maxNesting {
+			return nil, ErrMaxDepth
+		}
+		if tok == '[' {
+			arr := &json.Array{}
+			for d.More() {
+				item, err := parseNext(d, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				arr.Value = append(arr.Value, item)
+			}
+			_, err := d.Token()
+			return arr, err
+		}
+		obj := &json.Object{Value: map[json.Key]json.Value{}}
+		for d.More() {
+			key, err := d.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := parseNext(d, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			obj.Value[json.Key{Value: key.(string)}] = v
+		}
+		_, err := d.Token()
+		return obj, err
+	case string:
+		return &json.String{Value: tok}, nil
+	case stdjson.Number:
+		return parseNumber(string(tok))
+	case bool:
+		return &json.Bool{Value: tok}, nil
+	case nil:
+		return &json.Null{}, nil
+	}
+	return nil, fmt.Errorf("unexpected %v", tok)
+}
+
+// parseNext reads the next value, at depth, from d.
+func parseNext(d *stdjson.Decoder, depth int) (json.Value, error) {
+	tok, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+	return parseValue(d, tok, depth)
+}
+
+// parseNumber returns the number written as text: *json.Integer or
+// *json.Number if it can hold it exactly, *Decimal otherwise.
+func parseNumber(text string) (json.Value, error) {
+	if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return &json.Integer{Value: i}, nil
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err == nil && strconv.FormatFloat(f, 'g', -1, 64) == text {
+		return &json.Number{Value: f}, nil
+	}
+	r, ok := new(big.Rat).SetString(text)
+	if !ok {
+		// big.Rat refuses exponents that would take too much memory.
+		return nil, fmt.Errorf("number %s is out of range", text)
+	}
+	if err == nil && floatRat(f).Cmp(r) == 0 {
+		// Written differently, e.g. 1.50 or 1e2.
+		return &json.Number{Value: f}, nil
+	}
+	// f is ±Inf if the number is out of range of float64.
+	return &Decimal{Number: json.Number{Value: f}, Text: text, rat: r}, nil
+}
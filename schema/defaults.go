@@ -0,0 +1,89 @@
+package schema
+
+import (
+	json "github.com/cesanta/ucl"
+)
+
+// ApplyDefaults returns a copy of val with the missing properties set to the
+// "default" of their schemas in "properties", and validates the copy like
+// ValidateAll does. The defaults are filled in before the object is checked,
+// so they count for "required", and they are validated like any other
+// property, which also fills in the properties missing from them. Defaults in
+// subschemas applied only to see if the value is valid against them, like the
+// ones in "anyOf", are ignored.
+func (v *Validator) ApplyDefaults(val json.Value) (json.Value, error) {
+	val = copyValue(val)
+	s := v.newState(0)
+	s.defaults = true
+	if err := s.validate(v.root, "", val); err != nil && err != errTooManyErrors {
+		return val, err
+	}
+	if len(s.errs.errs) > 0 {
+		return val, Errors(s.errs.errs)
+	}
+	return val, nil
+}
+
+// fillDefaults adds the properties of n that are missing from val and have a
+// default.
+func fillDefaults(n *node, val *json.Object) {
+	for prop, sub := range n.properties {
+		if _, found := val.Lookup(prop); found {
+			continue
+		}
+		if d := sub.defaultValue(); d != nil {
+			val.Value[json.Key{Value: prop}] = copyValue(d)
+		}
+	}
+}
+
+// defaultValue returns the value of "default" in the schema, or in the one it
+// refers to with "$ref", nil if there is none.
+func (n *node) defaultValue() json.Value {
+	for _, a := range n.annotations {
+		if a.keyword == "default" {
+			return a.value
+		}
+	}
+	if n.ref != nil {
+		// Chains of "$ref"s always end, see findCycle.
+		return n.ref.defaultValue()
+	}
+	return nil
+}
+
+// copyValue returns a deep copy of v.
+func copyValue(v json.Value) json.Value {
+	switch v := v.(type) {
+	case *json.Object:
+		c := *v
+		c.Value = make(map[json.Key]json.Value, len(v.Value))
+		for k, item := range v.Value {
+			c.Value[k] = copyValue(item)
+		}
+		return &c
+	case *json.Array:
+		c := *v
+		c.Value = make([]json.Value, len(v.Value))
+		for i, item := range v.Value {
+			c.Value[i] = copyValue(item)
+		}
+		return &c
+	case *json.String:
+		c := *v
+		return &c
+	case *json.Number:
+		c := *v
+		return &c
+	case *json.Integer:
+		c := *v
+		return &c
+	case *Decimal:
+		c := *v
+		return &c
+	case *json.Bool:
+		c := *v
+		return &c
+	}
+	return v
+}
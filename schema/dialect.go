@@ -0,0 +1,416 @@
+package schema
+
+import (
+	"fmt"
+
+	json "github.com/cesanta/ucl"
+)
+
+// Dialect is a version of the JSON Schema specification.
+type Dialect struct {
+	name string
+	uri  string
+
+	// idKeyword is the keyword that changes the base URI, "id" or "$id".
+	idKeyword string
+	// anchorKeyword is the keyword that names a schema so it can be referred
+	// to by a plain fragment, "" if the dialect uses idKeyword for that.
+	anchorKeyword string
+	// refSiblings is set if keywords next to "$ref" apply too, rather than
+	// being ignored.
+	refSiblings bool
+	// booleanSchemas is set if true and false are valid schemas.
+	booleanSchemas bool
+	// booleanExclusiveLimits is set if "exclusiveMaximum" and
+	// "exclusiveMinimum" are booleans modifying "maximum" and "minimum"
+	// rather than limits on their own.
+	booleanExclusiveLimits bool
+	// integerFloats is set if numbers with zero fractional part, like 1.0,
+	// are integers.
+	integerFloats bool
+	// emptyArrays is set if "required", "enum" and array values in
+	// "dependencies" can be empty.
+	emptyArrays bool
+	// formatAnnotation is set if "format" is only an annotation and does not
+	// make values invalid.
+	formatAnnotation bool
+	// prefixItems is set if "items" applies to the items after
+	// "prefixItems", instead of "items" with an array and "additionalItems".
+	prefixItems bool
+	// containsEvaluates is set if items valid against "contains" count as
+	// evaluated for "unevaluatedItems".
+	containsEvaluates bool
+	// allowed restricts keywords to the ones from the vocabularies of a
+	// custom meta-schema, nil if all the keywords of the dialect apply.
+	allowed map[string]bool
+
+	// keywords has a checker for every keyword known in the dialect.
+	keywords map[string]keywordChecker
+	// subschemaKeywords are the keywords with a schema or an array of
+	// schemas as their value, subschemaMapKeywords are the ones with an
+	// object of schemas.
+	subschemaKeywords    []string
+	subschemaMapKeywords []string
+}
+
+// String returns the name of the dialect, e.g. "draft-04".
+func (d *Dialect) String() string {
+	return d.name
+}
+
+// URI returns the URI of the meta-schema of the dialect, which is what
+// "$schema" is set to in schemas written for it.
+func (d *Dialect) URI() string {
+	return d.uri
+}
+
+func (d *Dialect) knows(keyword string) bool {
+	_, found := d.keywords[keyword]
+	return found
+}
+
+var (
+	// Draft04 is http://json-schema.org/draft-04/schema#.
+	Draft04 = &Dialect{
+		name:                   "draft-04",
+		uri:                    "http://json-schema.org/draft-04/schema#",
+		idKeyword:              "id",
+		booleanExclusiveLimits: true,
+		keywords: map[string]keywordChecker{
+			"type":                 validateType,
+			"id":                   validateURI,
+			"$schema":              validateURI,
+			"title":                validateString,
+			"description":          validateString,
+			"multipleOf":           validateMultipleOf,
+			"maximum":              validateNumber,
+			"minimum":              validateNumber,
+			"exclusiveMaximum":     validateBoolean,
+			"exclusiveMinimum":     validateBoolean,
+			"minLength":            validateNonNegativeInteger,
+			"maxLength":            validateNonNegativeInteger,
+			"pattern":              validatePattern,
+			"additionalItems":      validateBoolOrSchema,
+			"items":                validateItems,
+			"maxItems":             validateNonNegativeInteger,
+			"minItems":             validateNonNegativeInteger,
+			"uniqueItems":          validateBoolean,
+			"maxProperties":        validateNonNegativeInteger,
+			"minProperties":        validateNonNegativeInteger,
+			"required":             validateStringArray,
+			"additionalProperties": validateBoolOrSchema,
+			"definitions":          validateSchemaCollection,
+			"properties":           validateSchemaCollection,
+			"patternProperties":    validateSchemaCollection,
+			"dependencies":         validateDependencies,
+			"enum":                 validateEnum,
+			"allOf":                validateSchemaArray,
+			"anyOf":                validateSchemaArray,
+			"oneOf":                validateSchemaArray,
+			"not":                  (*Dialect).validateSchema,
+		},
+		subschemaKeywords:    []string{"additionalItems", "additionalProperties", "not", "items", "allOf", "anyOf", "oneOf"},
+		subschemaMapKeywords: []string{"definitions", "properties", "patternProperties", "dependencies"},
+	}
+
+	// Draft06 is http://json-schema.org/draft-06/schema#.
+	Draft06 = &Dialect{
+		name:           "draft-06",
+		uri:            "http://json-schema.org/draft-06/schema#",
+		idKeyword:      "$id",
+		booleanSchemas: true,
+		integerFloats:  true,
+		emptyArrays:    true,
+		keywords: map[string]keywordChecker{
+			"type":                 validateType,
+			"$id":                  validateURI,
+			"$schema":              validateURI,
+			"title":                validateString,
+			"description":          validateString,
+			"default":              validateAnything,
+			"examples":             validateAnyArray,
+			"multipleOf":           validateMultipleOf,
+			"maximum":              validateNumber,
+			"minimum":              validateNumber,
+			"exclusiveMaximum":     validateNumber,
+			"exclusiveMinimum":     validateNumber,
+			"minLength":            validateNonNegativeInteger,
+			"maxLength":            validateNonNegativeInteger,
+			"pattern":              validatePattern,
+			"additionalItems":      (*Dialect).validateSchema,
+			"items":                validateItems,
+			"maxItems":             validateNonNegativeInteger,
+			"minItems":             validateNonNegativeInteger,
+			"uniqueItems":          validateBoolean,
+			"contains":             (*Dialect).validateSchema,
+			"maxProperties":        validateNonNegativeInteger,
+			"minProperties":        validateNonNegativeInteger,
+			"required":             validateStringArray,
+			"additionalProperties": (*Dialect).validateSchema,
+			"definitions":          validateSchemaCollection,
+			"properties":           validateSchemaCollection,
+			"patternProperties":    validateSchemaCollection,
+			"dependencies":         validateDependencies,
+			"propertyNames":        (*Dialect).validateSchema,
+			"const":                validateAnything,
+			"enum":                 validateEnum,
+			"allOf":                validateSchemaArray,
+			"anyOf":                validateSchemaArray,
+			"oneOf":                validateSchemaArray,
+			"not":                  (*Dialect).validateSchema,
+		},
+		subschemaKeywords:    []string{"additionalItems", "additionalProperties", "not", "items", "allOf", "anyOf", "oneOf", "contains", "propertyNames"},
+		subschemaMapKeywords: []string{"definitions", "properties", "patternProperties", "dependencies"},
+	}
+
+	// Draft07 is http://json-schema.org/draft-07/schema#.
+	Draft07 = &Dialect{
+		name:           "draft-07",
+		uri:            "http://json-schema.org/draft-07/schema#",
+		idKeyword:      "$id",
+		booleanSchemas: true,
+		integerFloats:  true,
+		emptyArrays:    true,
+		keywords: withKeywords(Draft06.keywords, map[string]keywordChecker{
+			"$comment":         validateString,
+			"if":               (*Dialect).validateSchema,
+			"then":             (*Dialect).validateSchema,
+			"else":             (*Dialect).validateSchema,
+			"readOnly":         validateBoolean,
+			"writeOnly":        validateBoolean,
+			"contentMediaType": validateString,
+			"contentEncoding":  validateString,
+		}),
+		subschemaKeywords:    []string{"additionalItems", "additionalProperties", "not", "items", "allOf", "anyOf", "oneOf", "contains", "propertyNames", "if", "then", "else"},
+		subschemaMapKeywords: []string{"definitions", "properties", "patternProperties", "dependencies"},
+	}
+
+	// Draft201909 is https://json-schema.org/draft/2019-09/schema.
+	// "definitions" is still accepted, like the meta-schema does for
+	// compatibility. "dependencies" is split into "dependentRequired" and
+	// "dependentSchemas", so it is not a keyword anymore.
+	Draft201909 = &Dialect{
+		name:             "2019-09",
+		uri:              "https://json-schema.org/draft/2019-09/schema",
+		idKeyword:        "$id",
+		anchorKeyword:    "$anchor",
+		refSiblings:      true,
+		booleanSchemas:   true,
+		integerFloats:    true,
+		emptyArrays:      true,
+		formatAnnotation: true,
+		keywords: withKeywords(withoutKeywords(Draft07.keywords, "dependencies"), map[string]keywordChecker{
+			"$anchor":               anchorChecker(anchorRe201909),
+			"$defs":                 validateSchemaCollection,
+			"$recursiveRef":         validateURI,
+			"$recursiveAnchor":      validateBoolean,
+			"$vocabulary":           validateVocabulary,
+			"deprecated":            validateBoolean,
+			"dependentRequired":     validateDependentRequired,
+			"dependentSchemas":      validateSchemaCollection,
+			"minContains":           validateNonNegativeInteger,
+			"maxContains":           validateNonNegativeInteger,
+			"unevaluatedItems":      (*Dialect).validateSchema,
+			"unevaluatedProperties": (*Dialect).validateSchema,
+			"contentSchema":         (*Dialect).validateSchema,
+		}),
+		subschemaKeywords: []string{"additionalItems", "additionalProperties", "not", "items", "allOf", "anyOf", "oneOf", "contains", "propertyNames", "if", "then", "else",
+			"unevaluatedItems", "unevaluatedProperties", "contentSchema"},
+		subschemaMapKeywords: []string{"definitions", "$defs", "properties", "patternProperties", "dependencies", "dependentSchemas"},
+	}
+
+	// Draft202012 is https://json-schema.org/draft/2020-12/schema. The array
+	// form of "items" is replaced by "prefixItems", and "$recursiveRef" by
+	// "$dynamicRef".
+	Draft202012 = &Dialect{
+		name:              "2020-12",
+		uri:               "https://json-schema.org/draft/2020-12/schema",
+		idKeyword:         "$id",
+		anchorKeyword:     "$anchor",
+		refSiblings:       true,
+		booleanSchemas:    true,
+		integerFloats:     true,
+		emptyArrays:       true,
+		formatAnnotation:  true,
+		prefixItems:       true,
+		containsEvaluates: true,
+		keywords: withKeywords(withoutKeywords(Draft201909.keywords, "additionalItems", "$recursiveRef", "$recursiveAnchor"), map[string]keywordChecker{
+			"$anchor":        anchorChecker(anchorRe202012),
+			"$dynamicAnchor": anchorChecker(anchorRe202012),
+			"$dynamicRef":    validateURI,
+			"prefixItems":    validateSchemaArray,
+			"items":          (*Dialect).validateSchema,
+		}),
+		subschemaKeywords: []string{"prefixItems", "additionalProperties", "not", "items", "allOf", "anyOf", "oneOf", "contains", "propertyNames", "if", "then", "else",
+			"unevaluatedItems", "unevaluatedProperties", "contentSchema"},
+		subschemaMapKeywords: []string{"definitions", "$defs", "properties", "patternProperties", "dependencies", "dependentSchemas"},
+	}
+)
+
+var dialects []*Dialect
+
+func init() {
+	// Set here to break the initialization cycle: checkers in the keyword
+	// tables look up embedded dialects in this list.
+	dialects = []*Dialect{Draft04, Draft06, Draft07, Draft201909, Draft202012}
+}
+
+// vocabularies has keywords of the 2020-12 vocabularies. Custom meta-schemas
+// can pick which of them apply with "$vocabulary".
+var vocabularies = map[string][]string{
+	"https://json-schema.org/draft/2020-12/vocab/core": {
+		"$id", "$schema", "$ref", "$anchor", "$dynamicRef", "$dynamicAnchor", "$vocabulary", "$comment", "$defs",
+		// Not in the vocabulary, but the meta-schema still has them.
+		"definitions", "dependencies"},
+	"https://json-schema.org/draft/2020-12/vocab/applicator": {
+		"prefixItems", "items", "contains", "additionalProperties", "properties", "patternProperties", "dependentSchemas",
+		"propertyNames", "if", "then", "else", "allOf", "anyOf", "oneOf", "not"},
+	"https://json-schema.org/draft/2020-12/vocab/unevaluated": {"unevaluatedItems", "unevaluatedProperties"},
+	"https://json-schema.org/draft/2020-12/vocab/validation": {
+		"type", "const", "enum", "multipleOf", "maximum", "exclusiveMaximum", "minimum", "exclusiveMinimum",
+		"maxLength", "minLength", "pattern", "maxItems", "minItems", "uniqueItems", "maxContains", "minContains",
+		"maxProperties", "minProperties", "required", "dependentRequired"},
+	"https://json-schema.org/draft/2020-12/vocab/meta-data": {
+		"title", "description", "default", "deprecated", "readOnly", "writeOnly", "examples"},
+	"https://json-schema.org/draft/2020-12/vocab/format-annotation": {"format"},
+	"https://json-schema.org/draft/2020-12/vocab/format-assertion":  {"format"},
+	"https://json-schema.org/draft/2020-12/vocab/content":           {"contentEncoding", "contentMediaType", "contentSchema"},
+}
+
+// metaSchemaDialect returns the dialect for schemas with "$schema" set to uri,
+// which is not one of the known dialects. The meta-schema is fetched with
+// loader. If it has "$vocabulary", only the keywords from the listed
+// vocabularies apply, otherwise the meta-schema is expected to extend one of
+// the known dialects. It returns an error if the meta-schema can't be fetched
+// or is not based on a known dialect.
+func metaSchemaDialect(loader *Loader, uri string) (*Dialect, error) {
+	meta, err := loader.Get(trimFragment(uri))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get meta-schema %q: %s", uri, err)
+	}
+	base := DetectDialect(meta)
+	if base == nil {
+		return nil, fmt.Errorf("meta-schema %q is not based on a known dialect", uri)
+	}
+	obj, ok := meta.(*json.Object)
+	if !ok {
+		return base, nil
+	}
+	vocab, ok := obj.Find("$vocabulary").(*json.Object)
+	if !ok || base != Draft202012 {
+		return base, nil
+	}
+	d := *base
+	d.name = uri
+	d.uri = uri
+	d.allowed = map[string]bool{}
+	for _, v := range sortedKeys(vocab) {
+		keywords, known := vocabularies[v]
+		if !known {
+			if required, ok := vocab.Find(v).(*json.Bool); ok && required.Value {
+				return nil, fmt.Errorf("meta-schema %q requires unknown vocabulary %q", uri, v)
+			}
+			continue
+		}
+		for _, k := range keywords {
+			d.allowed[k] = true
+		}
+		if v == "https://json-schema.org/draft/2020-12/vocab/format-assertion" {
+			d.formatAnnotation = false
+		}
+	}
+	return &d, nil
+}
+
+// filterKeywords returns schema with only the keywords allowed by d.
+func (d *Dialect) filterKeywords(schema *json.Object) *json.Object {
+	if d.allowed == nil {
+		return schema
+	}
+	r := &json.Object{Value: map[json.Key]json.Value{}}
+	for k, v := range schema.Value {
+		if d.allowed[k.Value] {
+			r.Value[k] = v
+		}
+	}
+	return r
+}
+
+func withoutKeywords(base map[string]keywordChecker, keywords ...string) map[string]keywordChecker {
+	r := withKeywords(base, nil)
+	for _, k := range keywords {
+		delete(r, k)
+	}
+	return r
+}
+
+// withKeywords returns a copy of base with extra keywords added.
+func withKeywords(base map[string]keywordChecker, extra map[string]keywordChecker) map[string]keywordChecker {
+	r := map[string]keywordChecker{}
+	for k, v := range base {
+		r[k] = v
+	}
+	for k, v := range extra {
+		r[k] = v
+	}
+	return r
+}
+
+// DetectDialect returns the dialect named by "$schema" in the schema, or nil
+// if it is not set or is not a known dialect.
+func DetectDialect(schema json.Value) *Dialect {
+	s, ok := schema.(*json.Object)
+	if !ok {
+		return nil
+	}
+	uri, ok := s.Find("$schema").(*json.String)
+	if !ok {
+		return nil
+	}
+	for _, d := range dialects {
+		if sameURI(uri.Value, d.uri) {
+			return d
+		}
+	}
+	return nil
+}
+
+// DialectByName returns the dialect with the given name, as returned by
+// String, or meta-schema URI. It returns nil if there is no such dialect.
+func DialectByName(name string) *Dialect {
+	for _, d := range dialects {
+		if name == d.name || sameURI(name, d.uri) {
+			return d
+		}
+	}
+	return nil
+}
+
+// embeddedDialect returns the dialect of an embedded schema resource: a
+// subschema with its own id and "$schema" naming one of the known dialects. It
+// returns nil if schema is not such a resource.
+func embeddedDialect(schema *json.Object) *Dialect {
+	e := DetectDialect(schema)
+	if e == nil {
+		return nil
+	}
+	if _, ok := schema.Find(e.idKeyword).(*json.String); !ok {
+		return nil
+	}
+	return e
+}
+
+// sameURI compares meta-schema URIs ignoring the empty fragment, since both
+// "http://json-schema.org/draft-04/schema#" and
+// "http://json-schema.org/draft-04/schema" are used in the wild.
+func sameURI(a string, b string) bool {
+	return trimFragment(a) == trimFragment(b)
+}
+
+// trimFragment removes the empty fragment from the URI.
+func trimFragment(s string) string {
+	if len(s) > 0 && s[len(s)-1] == '#' {
+		return s[:len(s)-1]
+	}
+	return s
+}
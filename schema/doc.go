@@ -1,14 +1,26 @@
-// Package schema implements JSON Schema draft 04 specification
-// (http://json-schema.org/documentation.html).
-// It passes all the tests from https://github.com/json-schema/JSON-Schema-Test-Suite
-// except for optional/bignum.json, but it doesn't mean that it's free of bugs,
-// especially in scope alteration and resolution, since that part is not entrirely
-// clear.
+// Package schema implements JSON Schema draft 04, draft 06, draft 07, 2019-09
+// and 2020-12 specifications (http://json-schema.org/documentation.html). The
+// dialect is picked based on "$schema", schemas without it are treated as
+// draft 04 unless DefaultDialect says otherwise. Since 2019-09 "format" is
+// only an annotation and does not affect validation, unless AssertFormats is
+// used.
+// It is tested with https://github.com/json-schema/JSON-Schema-Test-Suite: all
+// the required tests of draft 4, draft 6, draft 7, 2019-09 and 2020-12, and the
+// optional bignum.json, format.json and zeroTerminatedFloats.json of draft 4.
+// The rest of the optional tests are not run. Passing them doesn't mean that
+// it's free of bugs, especially in scope alteration and resolution, since that
+// part is not entrirely clear.
+//
+// Numbers are compared exactly, as the decimals they are written as, so
+// "multipleOf": 0.01 works as expected and large integers, like 64-bit IDs,
+// are not rounded. json.Parse rounds numbers with more significant digits
+// than float64 holds, Parse accepts the same input but keeps them as *Decimal,
+// so that is what schemas and values should be parsed with.
 //
 // Usage example:
 //
 //   // Load the schema.
-//   s, err := json.Parse(f)
+//   s, err := schema.Parse(f)
 //   if err != nil {
 //      log.Fatalf("Failed to parse the schema: %s", err)
 //   }
@@ -25,4 +37,63 @@
 //   if err := validator.Validate(data); err != nil {
 //      log.Fatalf("Validation failed: %s", err)
 //   }
+//
+//   // Or get all the violations at once, up to 100 of them.
+//   if err := validator.ValidateAll(data, 100); err != nil {
+//      for _, e := range err.(schema.Errors) {
+//         log.Printf("Violation: %s", e)
+//      }
+//   }
+//
+// Violations are reported as *ValidationError, which carries the location of
+// the offending value and the failing keyword:
+//
+//   var ve *schema.ValidationError
+//   if errors.As(err, &ve) {
+//      log.Printf("%s failed at %s", ve.Keyword, ve.InstancePath)
+//   }
+//
+// Values from untrusted sources can be validated with ValidateContext, which
+// stops once the context is done or the value needs more work than the
+// Limits set with WithLimits allow:
+//
+//   validator, err := schema.NewValidator(s, loader,
+//      schema.WithLimits(schema.Limits{MaxDepth: 64, MaxEvaluations: 100000, MaxErrors: 100}))
+//   ...
+//   ctx, cancel := context.WithTimeout(ctx, time.Second)
+//   defer cancel()
+//   err := validator.ValidateContext(ctx, data)
+//   var ie *schema.IncompleteError
+//   if errors.As(err, &ie) {
+//      log.Printf("Gave up: %s", ie.Err)
+//   }
+//
+// Large arrays and objects can be validated by several goroutines at once
+// with the Parallel option.
+//
+// The result can also be produced in one of the standard output formats, which
+// are meant to be serialized as JSON: FlagOutput, BasicOutput, DetailedOutput
+// and VerboseOutput.
+//
+// Annotate also collects annotations, like "title" or "default", from the
+// schemas that the value and its parts are valid against:
+//
+//   r := validator.Annotate(data)
+//   for _, a := range r.Annotations["/port"] {
+//      log.Printf("%s: %s", a.Keyword, a.Value)
+//   }
+//
+// ApplyDefaults returns a copy of the value with missing properties set to
+// their "default", and Coerce returns a copy with values converted to the
+// types required by the schema, e.g. "42" to 42.
+//
+// Custom keywords are added with the WithKeyword option of NewValidator, and
+// custom formats with WithFormat.
+//
+// Regular expressions use the syntax of the regexp package by default. Schemas
+// written for JavaScript validators, with lookaheads or backreferences, need
+// the ECMA-262 syntax required by the specification:
+//
+//   validator, err := schema.NewValidator(s, loader,
+//      schema.WithRegexpEngine(schema.ECMAScript(100*time.Millisecond)))
 package schema
@@ -0,0 +1,1235 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ECMAScript returns a RegexpEngine that follows the ECMA-262 syntax and
+// semantics with the "u" flag, as the JSON Schema specification requires:
+// lookarounds, backreferences, named groups, \p{...} property escapes, and
+// ASCII-only \d and \w. Matching backtracks, so some expressions take
+// exponential time on some strings. If timeout is greater than 0, matching a
+// single string gives up after that long with an error, which counts as a
+// violation of the keyword. The positions to backtrack to are kept on the
+// heap, so long strings take more memory rather than running out of stack.
+func ECMAScript(timeout time.Duration) RegexpEngine {
+	return ecmaEngine{timeout: timeout}
+}
+
+type ecmaEngine struct {
+	timeout time.Duration
+}
+
+func (e ecmaEngine) Compile(expr string) (Regexp, error) {
+	p := &ecmaParser{src: []rune(expr), names: map[string]int{}}
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	c := &ecmaCompiler{}
+	if len(p.backrefs) > 0 {
+		c.captures = true
+		c.regs = 2 * (p.groups + 1)
+	}
+	prog := c.program(root)
+	return &ecmaRegexp{expr: expr, prog: prog, regs: c.regs, captures: c.captures, timeout: e.timeout}, nil
+}
+
+type ecmaRegexp struct {
+	expr string
+	prog []ecmaInst
+	// regs is the number of registers prog uses, see ecmaCompiler.
+	regs     int
+	captures bool
+	timeout  time.Duration
+}
+
+func (re *ecmaRegexp) String() string {
+	return re.expr
+}
+
+func (re *ecmaRegexp) MatchString(s string) (bool, error) {
+	m := &ecmaMatcher{in: []rune(s), regs: make([]int, re.regs)}
+	if len(m.in) > math.MaxInt32 {
+		return false, errors.New("string is too long")
+	}
+	if re.captures {
+		m.caps = len(m.regs)
+	}
+	if re.timeout > 0 {
+		m.deadline = time.Now().Add(re.timeout)
+	}
+	for start := 0; start <= len(m.in); start++ {
+		for i := range m.regs {
+			m.regs[i] = -1
+		}
+		m.stack = m.stack[:0]
+		if m.run(re.prog, start, -1) {
+			return true, nil
+		}
+		if m.err == errECMATimeout {
+			return false, fmt.Errorf("matching took longer than %s", re.timeout)
+		}
+		if m.err != nil {
+			return false, m.err
+		}
+	}
+	return false, nil
+}
+
+// ecmaNode is a parsed part of an expression.
+type ecmaNode interface {
+	// compile appends the instructions that match the node to c.prog.
+	compile(c *ecmaCompiler)
+}
+
+// ecmaChar matches a single character in the set.
+type ecmaChar func(r rune) bool
+
+type ecmaSeq []ecmaNode
+
+type ecmaAlt []ecmaNode
+
+// ecmaGroup is a capturing group.
+type ecmaGroup struct {
+	index int
+	sub   ecmaNode
+}
+
+type ecmaBackref struct {
+	group int
+	name  string // Set for \k<name> until the group is known.
+}
+
+type ecmaAssertion rune // '^', '$', 'b' or 'B'.
+
+// ecmaLook is a lookahead or a lookbehind. Like in ECMA-262, once it matches
+// there is no backtracking into it, and groups captured by a positive one are
+// kept.
+type ecmaLook struct {
+	sub    ecmaNode
+	behind bool
+	negate bool
+}
+
+type ecmaRepeat struct {
+	sub      ecmaNode
+	min, max int // max is -1 if there is no limit.
+	greedy   bool
+	// Capturing groups with indices in (groupsFrom, groupsTo] are inside
+	// sub. They are reset before every iteration.
+	groupsFrom, groupsTo int
+}
+
+// ecmaNullable reports whether n can match an empty string.
+func ecmaNullable(n ecmaNode) bool {
+	switch n := n.(type) {
+	case ecmaChar:
+		return false
+	case ecmaSeq:
+		for _, sub := range n {
+			if !ecmaNullable(sub) {
+				return false
+			}
+		}
+		return true
+	case ecmaAlt:
+		for _, sub := range n {
+			if ecmaNullable(sub) {
+				return true
+			}
+		}
+		return false
+	case *ecmaGroup:
+		return ecmaNullable(n.sub)
+	case *ecmaRepeat:
+		return n.min == 0 || ecmaNullable(n.sub)
+	}
+	return true
+}
+
+type ecmaOp uint8
+
+const (
+	ecmaOpChar      ecmaOp = iota // Matches char.
+	ecmaOpChars                   // Matches repetitions of char, see ecmaMatcher.chars.
+	ecmaOpSplit                   // Goes on at x, backtracking to y.
+	ecmaOpJump                    // Goes on at x.
+	ecmaOpSave                    // Sets register x to the position.
+	ecmaOpCapture                 // Sets group x to span from register y to the position.
+	ecmaOpBackref                 // Matches what group x matched.
+	ecmaOpAssert                  // Checks the ecmaAssertion x.
+	ecmaOpLook                    // Checks the lookaround look with sub.
+	ecmaOpLoopStart               // Enters loop.
+	ecmaOpLoop                    // Starts another iteration of loop, or leaves it.
+	ecmaOpIteration               // Starts an iteration of loop.
+	ecmaOpIterated                // Ends an iteration of loop.
+	ecmaOpMatch                   // Matches if the position is the end, if any.
+)
+
+// ecmaInst is an instruction of a compiled expression. Matching starts at the
+// first one and goes on at the next one unless the instruction says
+// otherwise.
+type ecmaInst struct {
+	op   ecmaOp
+	x, y int
+	char ecmaChar
+	loop *ecmaLoop
+	look *ecmaLook
+	sub  []ecmaInst
+}
+
+// ecmaLoop is a compiled ecmaRepeat.
+type ecmaLoop struct {
+	*ecmaRepeat
+	// counter is the register that counts iterations, and start the one
+	// that holds the position the iteration started at. They are -1 if they
+	// are not needed: without limits there is nothing to count, and sub
+	// can't match an empty string if it is not nullable.
+	counter, start int
+	// loop is the ecmaOpLoop instruction, and exit the one after the loop.
+	loop, exit int
+}
+
+// ecmaCompiler compiles parsed expressions into instructions that work on
+// registers. If captures is set, the first 2*(groups+1) registers hold the
+// start and end of every capturing group, -1 if it did not match. Groups only
+// matter to backreferences, so they are not kept without them. The rest of
+// the registers are used by loops and groups to keep track of where they
+// started.
+type ecmaCompiler struct {
+	prog     []ecmaInst
+	regs     int
+	captures bool
+}
+
+// program compiles n into a separate list of instructions, which ends with
+// ecmaOpMatch.
+func (c *ecmaCompiler) program(n ecmaNode) []ecmaInst {
+	outer := c.prog
+	c.prog = nil
+	n.compile(c)
+	c.emit(ecmaInst{op: ecmaOpMatch})
+	prog := c.prog
+	c.prog = outer
+	return prog
+}
+
+// emit appends in and returns its index.
+func (c *ecmaCompiler) emit(in ecmaInst) int {
+	c.prog = append(c.prog, in)
+	return len(c.prog) - 1
+}
+
+// reg returns a new register.
+func (c *ecmaCompiler) reg() int {
+	c.regs++
+	return c.regs - 1
+}
+
+func (n ecmaChar) compile(c *ecmaCompiler) {
+	c.emit(ecmaInst{op: ecmaOpChar, char: n})
+}
+
+func (n ecmaSeq) compile(c *ecmaCompiler) {
+	for _, sub := range n {
+		sub.compile(c)
+	}
+}
+
+func (n ecmaAlt) compile(c *ecmaCompiler) {
+	var jumps []int
+	for _, alt := range n[:len(n)-1] {
+		split := c.emit(ecmaInst{op: ecmaOpSplit, x: len(c.prog) + 1})
+		alt.compile(c)
+		jumps = append(jumps, c.emit(ecmaInst{op: ecmaOpJump}))
+		c.prog[split].y = len(c.prog)
+	}
+	n[len(n)-1].compile(c)
+	for _, j := range jumps {
+		c.prog[j].x = len(c.prog)
+	}
+}
+
+func (n *ecmaGroup) compile(c *ecmaCompiler) {
+	if !c.captures {
+		n.sub.compile(c)
+		return
+	}
+	start := c.reg()
+	c.emit(ecmaInst{op: ecmaOpSave, x: start})
+	n.sub.compile(c)
+	c.emit(ecmaInst{op: ecmaOpCapture, x: n.index, y: start})
+}
+
+func (n *ecmaBackref) compile(c *ecmaCompiler) {
+	c.emit(ecmaInst{op: ecmaOpBackref, x: n.group})
+}
+
+func (n ecmaAssertion) compile(c *ecmaCompiler) {
+	c.emit(ecmaInst{op: ecmaOpAssert, x: int(n)})
+}
+
+func (n *ecmaLook) compile(c *ecmaCompiler) {
+	c.emit(ecmaInst{op: ecmaOpLook, look: n, sub: c.program(n.sub)})
+}
+
+func (n *ecmaRepeat) compile(c *ecmaCompiler) {
+	// Single characters are repeated without a frame for every iteration,
+	// even inside sequences of one node, or groups if they are not kept.
+	sub := n.sub
+	for {
+		if g, ok := sub.(*ecmaGroup); ok && !c.captures {
+			sub = g.sub
+		} else if seq, ok := sub.(ecmaSeq); ok && len(seq) == 1 {
+			sub = seq[0]
+		} else {
+			break
+		}
+	}
+	l := &ecmaLoop{ecmaRepeat: n, counter: -1, start: -1}
+	if ch, ok := sub.(ecmaChar); ok {
+		c.emit(ecmaInst{op: ecmaOpChars, char: ch, loop: l})
+		return
+	}
+	if n.min > 0 || n.max >= 0 {
+		l.counter = c.reg()
+	}
+	if ecmaNullable(sub) {
+		l.start = c.reg()
+	}
+	c.emit(ecmaInst{op: ecmaOpLoopStart, loop: l})
+	l.loop = c.emit(ecmaInst{op: ecmaOpLoop, loop: l})
+	c.emit(ecmaInst{op: ecmaOpIteration, loop: l})
+	sub.compile(c)
+	c.emit(ecmaInst{op: ecmaOpIterated, loop: l})
+	l.exit = len(c.prog)
+}
+
+// ecmaMatcher holds the state of matching a single string.
+type ecmaMatcher struct {
+	in   []rune
+	regs []int
+	// caps is the number of registers that hold groups, see ecmaCompiler.
+	caps     int
+	stack    []ecmaFrame
+	steps    int
+	deadline time.Time
+	// err is set once the deadline passes, which makes everything fail.
+	err error
+}
+
+// ecmaFrame is an entry of the backtracking stack. If pc is negative, it
+// undoes a change to register -pc-1, which was pos, and to the next one, which
+// was aux, unless aux is ecmaNone. Otherwise it is where matching goes on: at
+// instruction pc and position pos. aux is -1 then, unless the instruction is
+// ecmaOpChars, which goes on with another number of repetitions that started
+// at aux.
+type ecmaFrame struct {
+	pc, pos, aux int32
+}
+
+const ecmaNone = math.MinInt32
+
+var errECMATimeout = errors.New("timeout")
+
+// tick counts a step of matching and returns false if matching has to stop.
+func (m *ecmaMatcher) tick() bool {
+	if m.err != nil {
+		return false
+	}
+	m.steps++
+	if m.steps%1024 == 0 && !m.deadline.IsZero() && time.Now().After(m.deadline) {
+		m.err = errECMATimeout
+		return false
+	}
+	return true
+}
+
+// push adds a place to backtrack to.
+func (m *ecmaMatcher) push(pc, pos, aux int) {
+	m.stack = append(m.stack, ecmaFrame{pc: int32(pc), pos: int32(pos), aux: int32(aux)})
+}
+
+// set sets register r to v, keeping the old value on the stack.
+func (m *ecmaMatcher) set(r, v int) {
+	m.stack = append(m.stack, ecmaFrame{pc: int32(-r - 1), pos: int32(m.regs[r]), aux: ecmaNone})
+	m.regs[r] = v
+}
+
+// setPair sets registers r and r+1 to v and w, keeping the old values on the
+// stack.
+func (m *ecmaMatcher) setPair(r, v, w int) {
+	m.stack = append(m.stack, ecmaFrame{pc: int32(-r - 1), pos: int32(m.regs[r]), aux: int32(m.regs[r+1])})
+	m.regs[r], m.regs[r+1] = v, w
+}
+
+// run matches prog at position pos, backtracking as long as there are other
+// ways to try, and reports whether it matched. If end is not negative, the
+// match has to end there. Frames pushed while matching are left on the stack
+// if it matches, otherwise the stack is the same as before.
+func (m *ecmaMatcher) run(prog []ecmaInst, pos, end int) bool {
+	base := len(m.stack)
+	pc := 0
+	for {
+		if !m.tick() {
+			return false
+		}
+		in := &prog[pc]
+		ok := true
+		switch in.op {
+		case ecmaOpChar:
+			ok = pos < len(m.in) && in.char(m.in[pos])
+			pos++
+			pc++
+		case ecmaOpChars:
+			pos, ok = m.chars(in, pc, pos)
+			pc++
+		case ecmaOpSplit:
+			m.push(in.y, pos, -1)
+			pc = in.x
+		case ecmaOpJump:
+			pc = in.x
+		case ecmaOpSave:
+			m.set(in.x, pos)
+			pc++
+		case ecmaOpCapture:
+			m.setPair(2*in.x, m.regs[in.y], pos)
+			pc++
+		case ecmaOpBackref:
+			pos, ok = m.backref(in.x, pos)
+			pc++
+		case ecmaOpAssert:
+			ok = m.assert(ecmaAssertion(in.x), pos)
+			pc++
+		case ecmaOpLook:
+			ok = m.look(in, pos)
+			pc++
+		case ecmaOpLoopStart:
+			if in.loop.counter >= 0 {
+				m.set(in.loop.counter, 0)
+			}
+			pc++
+		case ecmaOpLoop:
+			l := in.loop
+			count := m.count(l)
+			switch {
+			case l.max >= 0 && count >= l.max:
+				pc = l.exit
+			case count < l.min:
+				pc++
+			case l.greedy:
+				m.push(l.exit, pos, -1)
+				pc++
+			default:
+				m.push(pc+1, pos, -1)
+				pc = l.exit
+			}
+		case ecmaOpIteration:
+			l := in.loop
+			if l.start >= 0 {
+				m.set(l.start, pos)
+			}
+			if m.caps > 0 {
+				for g := l.groupsFrom + 1; g <= l.groupsTo; g++ {
+					if m.regs[2*g] >= 0 || m.regs[2*g+1] >= 0 {
+						m.setPair(2*g, -1, -1)
+					}
+				}
+			}
+			pc++
+		case ecmaOpIterated:
+			l := in.loop
+			count := m.count(l)
+			// Iterations that match an empty string once the minimum is
+			// reached would repeat forever.
+			if l.start >= 0 && pos == m.regs[l.start] && count >= l.min {
+				ok = false
+				break
+			}
+			// Without a maximum the count only matters until it reaches
+			// the minimum.
+			if l.counter >= 0 && (count < l.min || l.max >= 0) {
+				m.set(l.counter, count+1)
+			}
+			pc = l.loop
+		case ecmaOpMatch:
+			if end < 0 || pos == end {
+				return true
+			}
+			ok = false
+		}
+		if !ok {
+			if pc, pos, ok = m.backtrack(prog, base); !ok {
+				return false
+			}
+		}
+	}
+}
+
+// backtrack pops frames off the stack, down to base, undoing changes to
+// registers until it finds where to go on matching prog, and returns that
+// instruction and position. It returns false if there is none.
+func (m *ecmaMatcher) backtrack(prog []ecmaInst, base int) (int, int, bool) {
+	for len(m.stack) > base {
+		f := m.stack[len(m.stack)-1]
+		m.stack = m.stack[:len(m.stack)-1]
+		if f.pc < 0 {
+			r := int(-f.pc - 1)
+			m.regs[r] = int(f.pos)
+			if f.aux != ecmaNone {
+				m.regs[r+1] = int(f.aux)
+			}
+			continue
+		}
+		pc, pos := int(f.pc), int(f.pos)
+		if f.aux < 0 {
+			return pc, pos, true
+		}
+		in := &prog[pc]
+		if in.loop.greedy {
+			pos--
+		} else {
+			pos++
+		}
+		if m.moreChars(in, int(f.aux), pos) {
+			m.push(pc, pos, int(f.aux))
+		}
+		return pc + 1, pos, true
+	}
+	return 0, 0, false
+}
+
+// count returns the number of iterations of l so far, which is only kept
+// track of up to the minimum if there is no maximum.
+func (m *ecmaMatcher) count(l *ecmaLoop) int {
+	if l.counter < 0 {
+		return l.min
+	}
+	return m.regs[l.counter]
+}
+
+// chars matches the repetitions of a single character of the ecmaOpChars
+// instruction in at pc, as many as possible if it is greedy, as few as
+// possible otherwise, and returns the position after them. Instead of a frame
+// for every position to backtrack to, there is only one, for the closest one,
+// since they are all in the run of matching characters that starts at pos.
+func (m *ecmaMatcher) chars(in *ecmaInst, pc, pos int) (int, bool) {
+	l := in.loop
+	start := pos
+	limit := l.min
+	if l.greedy {
+		limit = l.max
+	}
+	for (limit < 0 || pos-start < limit) && pos < len(m.in) && in.char(m.in[pos]) {
+		pos++
+	}
+	if pos-start < l.min {
+		return pos, false
+	}
+	if m.moreChars(in, start, pos) {
+		m.push(pc, pos, start)
+	}
+	return pos, true
+}
+
+// moreChars reports whether the ecmaOpChars instruction in, which started at
+// start, can go on with a different number of repetitions when it is at pos,
+// one less if it is greedy, one more otherwise.
+func (m *ecmaMatcher) moreChars(in *ecmaInst, start, pos int) bool {
+	l := in.loop
+	if l.greedy {
+		return pos > start+l.min
+	}
+	return (l.max < 0 || pos-start < l.max) && pos < len(m.in) && in.char(m.in[pos])
+}
+
+// backref matches what group matched at i.
+func (m *ecmaMatcher) backref(group, i int) (int, bool) {
+	start, end := m.regs[2*group], m.regs[2*group+1]
+	if start < 0 || end < 0 {
+		return i, true
+	}
+	if i+end-start > len(m.in) {
+		return i, false
+	}
+	for j := start; j < end; j++ {
+		if m.in[i+j-start] != m.in[j] {
+			return i, false
+		}
+	}
+	return i + end - start, true
+}
+
+func (m *ecmaMatcher) assert(a ecmaAssertion, i int) bool {
+	switch a {
+	case '^':
+		return i == 0
+	case '$':
+		return i == len(m.in)
+	case 'b':
+		return m.isWord(i-1) != m.isWord(i)
+	}
+	return m.isWord(i-1) == m.isWord(i)
+}
+
+func (m *ecmaMatcher) isWord(i int) bool {
+	return i >= 0 && i < len(m.in) && isECMAWord(m.in[i])
+}
+
+// look reports whether the lookaround of the ecmaOpLook instruction in
+// matches at i. Its frames are dropped once it is done, so there is no
+// backtracking into it, except for the ones that restore the groups a
+// positive one captured.
+func (m *ecmaMatcher) look(in *ecmaInst, i int) bool {
+	caps := m.regs[:m.caps]
+	saved := append([]int(nil), caps...)
+	base := len(m.stack)
+	matched := false
+	if in.look.behind {
+		// Lookbehinds are matched forwards from every possible start,
+		// longest first.
+		for j := 0; j <= i && !matched && m.err == nil; j++ {
+			matched = m.run(in.sub, j, i)
+		}
+	} else {
+		matched = m.run(in.sub, i, -1)
+	}
+	m.stack = m.stack[:base]
+	if m.err != nil {
+		return false
+	}
+	if in.look.negate || !matched {
+		copy(caps, saved)
+		return in.look.negate != matched
+	}
+	for r := range caps {
+		if caps[r] != saved[r] {
+			m.stack = append(m.stack, ecmaFrame{pc: int32(-r - 1), pos: int32(saved[r]), aux: ecmaNone})
+		}
+	}
+	return true
+}
+
+// ecmaParser parses expressions following the grammar in ECMA-262, section
+// 22.2.1, with the "u" flag.
+type ecmaParser struct {
+	src    []rune
+	pos    int
+	groups int // Number of capturing groups so far.
+	names  map[string]int
+	// backrefs are checked once all the groups are known, since they can
+	// refer to groups that come after them.
+	backrefs []*ecmaBackref
+}
+
+func (p *ecmaParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%s at position %d", fmt.Sprintf(format, args...), p.pos)
+}
+
+func (p *ecmaParser) more() bool {
+	return p.pos < len(p.src)
+}
+
+// next reports whether the rest of the expression starts with s.
+func (p *ecmaParser) next(s string) bool {
+	r := []rune(s)
+	if p.pos+len(r) > len(p.src) {
+		return false
+	}
+	return string(p.src[p.pos:p.pos+len(r)]) == s
+}
+
+func (p *ecmaParser) parse() (ecmaNode, error) {
+	n, err := p.disjunction()
+	if err != nil {
+		return nil, err
+	}
+	if p.more() {
+		return nil, p.errorf("unmatched ')'")
+	}
+	for _, b := range p.backrefs {
+		if b.name != "" {
+			index, found := p.names[b.name]
+			if !found {
+				return nil, fmt.Errorf("no group named %q", b.name)
+			}
+			b.group = index
+		} else if b.group > p.groups {
+			return nil, fmt.Errorf("no group %d", b.group)
+		}
+	}
+	return n, nil
+}
+
+func (p *ecmaParser) disjunction() (ecmaNode, error) {
+	var alts ecmaAlt
+	for {
+		seq, err := p.alternative()
+		if err != nil {
+			return nil, err
+		}
+		alts = append(alts, seq)
+		if !p.next("|") {
+			break
+		}
+		p.pos++
+	}
+	if len(alts) == 1 {
+		return alts[0], nil
+	}
+	return alts, nil
+}
+
+func (p *ecmaParser) alternative() (ecmaNode, error) {
+	var seq ecmaSeq
+	for p.more() && !p.next("|") && !p.next(")") {
+		n, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, n)
+	}
+	return seq, nil
+}
+
+func (p *ecmaParser) term() (ecmaNode, error) {
+	var assertion ecmaNode
+	switch {
+	case p.next("^"), p.next("$"):
+		assertion = ecmaAssertion(p.src[p.pos])
+		p.pos++
+	case p.next(`\b`), p.next(`\B`):
+		assertion = ecmaAssertion(p.src[p.pos+1])
+		p.pos += 2
+	case p.next("(?="), p.next("(?!"), p.next("(?<="), p.next("(?<!"):
+		look := &ecmaLook{behind: p.next("(?<")}
+		if look.behind {
+			p.pos++
+		}
+		look.negate = p.src[p.pos+2] == '!'
+		p.pos += 3
+		var err error
+		if look.sub, err = p.disjunction(); err != nil {
+			return nil, err
+		}
+		if !p.next(")") {
+			return nil, p.errorf("missing ')'")
+		}
+		p.pos++
+		assertion = look
+	}
+	if assertion != nil {
+		if p.quantifierNext() {
+			return nil, p.errorf("nothing to repeat")
+		}
+		return assertion, nil
+	}
+	groupsFrom := p.groups
+	atom, err := p.atom()
+	if err != nil {
+		return nil, err
+	}
+	if !p.quantifierNext() {
+		return atom, nil
+	}
+	r := &ecmaRepeat{sub: atom, groupsFrom: groupsFrom, groupsTo: p.groups}
+	if r.min, r.max, err = p.quantifier(); err != nil {
+		return nil, err
+	}
+	r.greedy = !p.next("?")
+	if !r.greedy {
+		p.pos++
+	}
+	return r, nil
+}
+
+func (p *ecmaParser) quantifierNext() bool {
+	return p.next("*") || p.next("+") || p.next("?") || p.next("{")
+}
+
+func (p *ecmaParser) quantifier() (min int, max int, err error) {
+	c := p.src[p.pos]
+	p.pos++
+	switch c {
+	case '*':
+		return 0, -1, nil
+	case '+':
+		return 1, -1, nil
+	case '?':
+		return 0, 1, nil
+	}
+	if min, err = p.decimal(); err != nil {
+		return 0, 0, err
+	}
+	max = min
+	if p.next(",") {
+		p.pos++
+		max = -1
+		if !p.next("}") {
+			if max, err = p.decimal(); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+	if !p.next("}") {
+		return 0, 0, p.errorf("incomplete quantifier")
+	}
+	p.pos++
+	if max >= 0 && max < min {
+		return 0, 0, p.errorf("numbers out of order in quantifier")
+	}
+	return min, max, nil
+}
+
+// decimal parses a non-empty sequence of digits. Numbers that are too large
+// to matter are capped.
+func (p *ecmaParser) decimal() (int, error) {
+	start := p.pos
+	for p.more() && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+		p.pos++
+	}
+	if p.pos == start {
+		return 0, p.errorf("expected a number")
+	}
+	n, err := strconv.Atoi(string(p.src[start:p.pos]))
+	if err != nil || n > 1<<30 {
+		n = 1 << 30
+	}
+	return n, nil
+}
+
+func (p *ecmaParser) atom() (ecmaNode, error) {
+	c := p.src[p.pos]
+	switch c {
+	case '.':
+		p.pos++
+		return ecmaChar(func(r rune) bool { return !isECMALineTerminator(r) }), nil
+	case '(':
+		p.pos++
+		group := &ecmaGroup{}
+		switch {
+		case p.next("?:"):
+			p.pos += 2
+		case p.next("?<"):
+			p.pos += 2
+			name, err := p.groupName()
+			if err != nil {
+				return nil, err
+			}
+			if _, found := p.names[name]; found {
+				return nil, p.errorf("duplicate group name %q", name)
+			}
+			p.groups++
+			group.index = p.groups
+			p.names[name] = group.index
+		case p.next("?"):
+			return nil, p.errorf("invalid group")
+		default:
+			p.groups++
+			group.index = p.groups
+		}
+		sub, err := p.disjunction()
+		if err != nil {
+			return nil, err
+		}
+		if !p.next(")") {
+			return nil, p.errorf("missing ')'")
+		}
+		p.pos++
+		if group.index == 0 {
+			return sub, nil
+		}
+		group.sub = sub
+		return group, nil
+	case '[':
+		p.pos++
+		return p.class()
+	case '\\':
+		p.pos++
+		return p.atomEscape()
+	case '*', '+', '?', '{':
+		return nil, p.errorf("nothing to repeat")
+	case ')', ']', '}':
+		return nil, p.errorf("unmatched '%c'", c)
+	}
+	p.pos++
+	return ecmaChar(func(r rune) bool { return r == c }), nil
+}
+
+// groupName parses the name of a group up to '>'.
+func (p *ecmaParser) groupName() (string, error) {
+	start := p.pos
+	for p.more() && p.src[p.pos] != '>' {
+		r := p.src[p.pos]
+		if !(r == '$' || r == '_' || unicode.IsLetter(r) || (p.pos > start && (unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Mc, r) || unicode.Is(unicode.Pc, r)))) {
+			return "", p.errorf("invalid group name")
+		}
+		p.pos++
+	}
+	if !p.more() || p.pos == start {
+		return "", p.errorf("invalid group name")
+	}
+	p.pos++
+	return string(p.src[start : p.pos-1]), nil
+}
+
+// atomEscape parses what comes after '\' outside of character classes.
+func (p *ecmaParser) atomEscape() (ecmaNode, error) {
+	if !p.more() {
+		return nil, p.errorf(`\ at end of pattern`)
+	}
+	c := p.src[p.pos]
+	switch {
+	case c >= '1' && c <= '9':
+		n, err := p.decimal()
+		if err != nil {
+			return nil, err
+		}
+		b := &ecmaBackref{group: n}
+		p.backrefs = append(p.backrefs, b)
+		return b, nil
+	case c == 'k':
+		p.pos++
+		if !p.next("<") {
+			return nil, p.errorf(`invalid named reference`)
+		}
+		p.pos++
+		name, err := p.groupName()
+		if err != nil {
+			return nil, err
+		}
+		b := &ecmaBackref{name: name}
+		p.backrefs = append(p.backrefs, b)
+		return b, nil
+	}
+	if set, ok, err := p.classEscape(); ok || err != nil {
+		return set, err
+	}
+	r, err := p.characterEscape(false)
+	if err != nil {
+		return nil, err
+	}
+	return ecmaChar(func(x rune) bool { return x == r }), nil
+}
+
+// classEscape parses \d, \D, \s, \S, \w, \W, \p{...} and \P{...}, without the
+// '\'. It returns false if there is none of them.
+func (p *ecmaParser) classEscape() (ecmaChar, bool, error) {
+	var set ecmaChar
+	c := p.src[p.pos]
+	switch c {
+	case 'd', 'D':
+		set = func(r rune) bool { return r >= '0' && r <= '9' }
+	case 's', 'S':
+		set = isECMASpace
+	case 'w', 'W':
+		set = isECMAWord
+	case 'p', 'P':
+		p.pos++
+		if !p.next("{") {
+			return nil, true, p.errorf("invalid property name")
+		}
+		end := p.pos
+		for end < len(p.src) && p.src[end] != '}' {
+			end++
+		}
+		if end == len(p.src) {
+			return nil, true, p.errorf("invalid property name")
+		}
+		var err error
+		if set, err = unicodeProperty(string(p.src[p.pos+1 : end])); err != nil {
+			return nil, true, p.errorf("%s", err)
+		}
+		p.pos = end
+	default:
+		return nil, false, nil
+	}
+	p.pos++
+	if unicode.IsUpper(c) {
+		in := set
+		set = func(r rune) bool { return !in(r) }
+	}
+	return set, true, nil
+}
+
+// characterEscape parses an escaped character, without the '\'.
+func (p *ecmaParser) characterEscape(inClass bool) (rune, error) {
+	c := p.src[p.pos]
+	p.pos++
+	switch c {
+	case 'f':
+		return '\f', nil
+	case 'n':
+		return '\n', nil
+	case 'r':
+		return '\r', nil
+	case 't':
+		return '\t', nil
+	case 'v':
+		return '\v', nil
+	case 'c':
+		if p.more() {
+			if l := p.src[p.pos]; l >= 'a' && l <= 'z' || l >= 'A' && l <= 'Z' {
+				p.pos++
+				return l % 32, nil
+			}
+		}
+		return 0, p.errorf(`invalid control escape`)
+	case '0':
+		if p.more() && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+			return 0, p.errorf("invalid decimal escape")
+		}
+		return 0, nil
+	case 'x':
+		return p.hex(2)
+	case 'u':
+		if p.next("{") {
+			p.pos++
+			start := p.pos
+			for p.more() && p.src[p.pos] != '}' {
+				p.pos++
+			}
+			if !p.more() {
+				return 0, p.errorf("invalid Unicode escape")
+			}
+			p.pos++
+			r, err := strconv.ParseUint(string(p.src[start:p.pos-1]), 16, 32)
+			if err != nil || r > unicode.MaxRune {
+				return 0, p.errorf("invalid Unicode escape")
+			}
+			return rune(r), nil
+		}
+		r, err := p.hex(4)
+		if err != nil {
+			return 0, err
+		}
+		// Surrogate pairs written as two escapes are a single character.
+		if r >= 0xD800 && r <= 0xDBFF && p.next(`\u`) {
+			pos := p.pos
+			p.pos += 2
+			if low, err := p.hex(4); err == nil && low >= 0xDC00 && low <= 0xDFFF {
+				return (r-0xD800)<<10 + (low - 0xDC00) + 0x10000, nil
+			}
+			p.pos = pos
+		}
+		return r, nil
+	case '^', '$', '\\', '.', '*', '+', '?', '(', ')', '[', ']', '{', '}', '|', '/':
+		return c, nil
+	case 'b':
+		if inClass {
+			return '\b', nil
+		}
+	case '-':
+		if inClass {
+			return '-', nil
+		}
+	}
+	p.pos--
+	return 0, p.errorf(`invalid escape '\%c'`, c)
+}
+
+// hex parses exactly n hexadecimal digits.
+func (p *ecmaParser) hex(n int) (rune, error) {
+	if p.pos+n > len(p.src) {
+		return 0, p.errorf("invalid escape")
+	}
+	r, err := strconv.ParseUint(string(p.src[p.pos:p.pos+n]), 16, 32)
+	if err != nil {
+		return 0, p.errorf("invalid escape")
+	}
+	p.pos += n
+	return rune(r), nil
+}
+
+// class parses a character class, after '['.
+func (p *ecmaParser) class() (ecmaNode, error) {
+	negate := p.next("^")
+	if negate {
+		p.pos++
+	}
+	var sets []ecmaChar
+	for !p.next("]") {
+		if !p.more() {
+			return nil, p.errorf("missing ']'")
+		}
+		lo, set, err := p.classAtom()
+		if err != nil {
+			return nil, err
+		}
+		if !p.next("-") || p.next("-]") {
+			if set == nil {
+				set = func(r rune) bool { return r == lo }
+			}
+			sets = append(sets, set)
+			continue
+		}
+		p.pos++
+		hi, hiSet, err := p.classAtom()
+		if err != nil {
+			return nil, err
+		}
+		if set != nil || hiSet != nil {
+			return nil, p.errorf("invalid character class range")
+		}
+		if lo > hi {
+			return nil, p.errorf("range out of order in character class")
+		}
+		sets = append(sets, func(r rune) bool { return r >= lo && r <= hi })
+	}
+	p.pos++
+	return ecmaChar(func(r rune) bool {
+		for _, set := range sets {
+			if set(r) {
+				return !negate
+			}
+		}
+		return negate
+	}), nil
+}
+
+// classAtom parses a character or a class escape in a character class.
+func (p *ecmaParser) classAtom() (rune, ecmaChar, error) {
+	if !p.more() {
+		return 0, nil, p.errorf("missing ']'")
+	}
+	c := p.src[p.pos]
+	p.pos++
+	if c != '\\' {
+		return c, nil, nil
+	}
+	if !p.more() {
+		return 0, nil, p.errorf(`\ at end of pattern`)
+	}
+	if set, ok, err := p.classEscape(); ok || err != nil {
+		return 0, set, err
+	}
+	r, err := p.characterEscape(true)
+	return r, nil, err
+}
+
+func isECMALineTerminator(r rune) bool {
+	return r == '\n' || r == '\r' || r == '\u2028' || r == '\u2029'
+}
+
+func isECMASpace(r rune) bool {
+	switch r {
+	case '\t', '\v', '\f', '\ufeff':
+		return true
+	}
+	return isECMALineTerminator(r) || unicode.Is(unicode.Zs, r)
+}
+
+func isECMAWord(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_'
+}
+
+// generalCategories maps the long names and aliases of general categories to
+// the short ones used by the unicode package.
+var generalCategories = map[string]string{
+	"Letter":                "L",
+	"Uppercase_Letter":      "Lu",
+	"Lowercase_Letter":      "Ll",
+	"Titlecase_Letter":      "Lt",
+	"Modifier_Letter":       "Lm",
+	"Other_Letter":          "Lo",
+	"Mark":                  "M",
+	"Combining_Mark":        "M",
+	"Spacing_Mark":          "Mc",
+	"Nonspacing_Mark":       "Mn",
+	"Enclosing_Mark":        "Me",
+	"Number":                "N",
+	"Decimal_Number":        "Nd",
+	"digit":                 "Nd",
+	"Letter_Number":         "Nl",
+	"Other_Number":          "No",
+	"Punctuation":           "P",
+	"punct":                 "P",
+	"Connector_Punctuation": "Pc",
+	"Dash_Punctuation":      "Pd",
+	"Open_Punctuation":      "Ps",
+	"Close_Punctuation":     "Pe",
+	"Initial_Punctuation":   "Pi",
+	"Final_Punctuation":     "Pf",
+	"Other_Punctuation":     "Po",
+	"Symbol":                "S",
+	"Math_Symbol":           "Sm",
+	"Currency_Symbol":       "Sc",
+	"Modifier_Symbol":       "Sk",
+	"Other_Symbol":          "So",
+	"Separator":             "Z",
+	"Space_Separator":       "Zs",
+	"Line_Separator":        "Zl",
+	"Paragraph_Separator":   "Zp",
+	"Other":                 "C",
+	"Control":               "Cc",
+	"cntrl":                 "Cc",
+	"Format":                "Cf",
+	"Surrogate":             "Cs",
+	"Private_Use":           "Co",
+	"Unassigned":            "Cn",
+	"Cased_Letter":          "LC",
+}
+
+func isAssigned(r rune) bool {
+	return unicode.In(r, unicode.L, unicode.M, unicode.N, unicode.P, unicode.S, unicode.Z, unicode.C)
+}
+
+// generalCategory returns the set of characters in the general category, nil
+// if there is no such category.
+func generalCategory(name string) ecmaChar {
+	if short, found := generalCategories[name]; found {
+		name = short
+	}
+	switch name {
+	case "LC":
+		return func(r rune) bool { return unicode.In(r, unicode.Lu, unicode.Ll, unicode.Lt) }
+	case "Cn":
+		return func(r rune) bool { return !isAssigned(r) }
+	case "C":
+		return func(r rune) bool { return unicode.Is(unicode.C, r) || !isAssigned(r) }
+	}
+	if t := unicode.Categories[name]; t != nil {
+		return func(r rune) bool { return unicode.Is(t, r) }
+	}
+	return nil
+}
+
+// unicodeProperty returns the set of characters matched by \p{spec}.
+func unicodeProperty(spec string) (ecmaChar, error) {
+	if i := strings.Index(spec, "="); i >= 0 {
+		name, value := spec[:i], spec[i+1:]
+		switch name {
+		case "General_Category", "gc":
+			if set := generalCategory(value); set != nil {
+				return set, nil
+			}
+		case "Script", "sc":
+			if t := unicode.Scripts[value]; t != nil {
+				return func(r rune) bool { return unicode.Is(t, r) }, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown Unicode property %q", spec)
+	}
+	if set := generalCategory(spec); set != nil {
+		return set, nil
+	}
+	switch spec {
+	case "Any":
+		return func(rune) bool { return true }, nil
+	case "ASCII":
+		return func(r rune) bool { return r < 0x80 }, nil
+	case "Assigned":
+		return isAssigned, nil
+	}
+	if t := unicode.Properties[spec]; t != nil {
+		return func(r rune) bool { return unicode.Is(t, r) }, nil
+	}
+	return nil, fmt.Errorf("unknown Unicode property %q", spec)
+}
@@ -0,0 +1,91 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ValidationError describes a single place where a value does not conform to
+// the schema. Use errors.As to get it out of the error returned by Validate or
+// ValidateAll.
+type ValidationError struct {
+	// InstancePath is a JSON Pointer to the offending value, "" being the
+	// whole value.
+	InstancePath string
+	// SchemaPath is the location of the failing keyword, e.g.
+	// "#/properties/foo/maxLength". After following "$ref" it is based on the
+	// reference, e.g. "#/definitions/bar/type" or
+	// "http://example.com/schema.json#/minimum".
+	SchemaPath string
+	// Keyword is the name of the failing keyword, e.g. "maxLength".
+	Keyword string
+	// Params holds the details specific to the keyword, e.g. "limit" for
+	// "maxLength" or "property" for "required".
+	Params map[string]interface{}
+	// Message is a human-readable description of the problem.
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%q: %s", "#"+e.InstancePath, e.Message)
+}
+
+type params map[string]interface{}
+
+func newValidationError(path string, schemaPath string, keyword string, p params, format string, args ...interface{}) *ValidationError {
+	return &ValidationError{
+		InstancePath: path,
+		SchemaPath:   schemaPath + "/" + keyword,
+		Keyword:      keyword,
+		Params:       p,
+		Message:      fmt.Sprintf(format, args...),
+	}
+}
+
+// Errors is a list of violations returned by Validator.ValidateAll.
+type Errors []error
+
+func (e Errors) Error() string {
+	s := make([]string, len(e))
+	for i, err := range e {
+		s[i] = err.Error()
+	}
+	return strings.Join(s, "\n")
+}
+
+// Unwrap returns the individual errors, so errors.Is and errors.As can look
+// into each of them.
+func (e Errors) Unwrap() []error {
+	return e
+}
+
+// errTooManyErrors is returned by errorList.add once the limit is reached. It
+// is used to unwind the validation and never escapes the package.
+var errTooManyErrors = errors.New("too many errors")
+
+// errorList accumulates violations found during validation.
+type errorList struct {
+	errs []error
+	max  int // 0 means no limit.
+	// limiter is set if validation can be stopped by ValidateContext.
+	// Violations found after that are not reliable, since the errors
+	// returned by validation in isolation are not propagated, so they are
+	// dropped.
+	limiter *limiter
+}
+
+// add records err. It returns a non-nil error if validation needs to stop,
+// which the caller is expected to return as is.
+func (l *errorList) add(err error) error {
+	if l.limiter != nil {
+		if err := l.limiter.stopped(); err != nil {
+			return err
+		}
+	}
+	l.errs = append(l.errs, err)
+	if l.max > 0 && len(l.errs) >= l.max {
+		return errTooManyErrors
+	}
+	return nil
+}
@@ -4,45 +4,318 @@ import (
 	"fmt"
 	"net/url"
 	"regexp"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/asaskevich/govalidator"
+	"golang.org/x/net/idna"
 )
 
 var (
 	hostnameRe = regexp.MustCompile(`^([a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])(\.([a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9\-]{0,61}[a-zA-Z0-9]))*$`)
+	// RFC 3339 full-time, time.Parse is too lenient about the number of digits.
+	timeRe                = regexp.MustCompile(`^\d{2}:\d{2}:\d{2}(\.\d+)?([zZ]|[+-]\d{2}:\d{2})$`)
+	jsonPointerRe         = regexp.MustCompile(`^(/([^~/]|~[01])*)*$`)
+	relativeJSONPointerRe = regexp.MustCompile(`^(0|[1-9][0-9]*)(#|(/([^~/]|~[01])*)*)$`)
+	uriTemplateRe         = regexp.MustCompile(`^([^{}]|\{[^{}]+\})*$`)
+	uuidRe                = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	// RFC 3339, appendix A. Components can't be skipped in the middle, e.g.
+	// "P1Y1D" is not valid.
+	durationRe = regexp.MustCompile(`^P((\d+D|\d+M(\d+D)?|\d+Y(\d+M(\d+D)?)?)(T(\d+H(\d+M(\d+S)?)?|\d+M(\d+S)?|\d+S))?|T(\d+H(\d+M(\d+S)?)?|\d+M(\d+S)?|\d+S)|\d+W)$`)
+	// uriForbiddenChars can't appear anywhere in a URI or IRI.
+	uriForbiddenChars = " \"<>\\^`{|}"
 )
 
-func verifyFormat(val string, format string) error {
-	switch format {
-	case "date-time":
+// idnaProfile checks internationalized hostnames, see RFC 5891.
+var idnaProfile = idna.New(
+	idna.MapForLookup(),
+	idna.BidiRule(),
+	idna.VerifyDNSLength(true),
+	idna.CheckHyphens(true),
+	idna.CheckJoiners(true),
+	idna.StrictDomainName(true),
+)
+
+// formats has checkers for the built-in formats.
+var formats = map[string]func(val string) error{
+	"date-time": func(val string) error {
 		_, err := time.Parse(time.RFC3339, val)
 		return err
-	case "email":
+	},
+	"email": func(val string) error {
 		if !govalidator.IsEmail(val) {
 			return fmt.Errorf("%q is not a valid email", val)
 		}
-	case "hostname":
+		return nil
+	},
+	"hostname": func(val string) error {
 		if !hostnameRe.MatchString(val) || len(val) > 255 {
 			return fmt.Errorf("%q is not a valid hostname", val)
 		}
-	case "ipv4":
+		return nil
+	},
+	"ipv4": func(val string) error {
 		if !govalidator.IsIPv4(val) {
 			return fmt.Errorf("%q is not a valid IPv4 address", val)
 		}
-	case "ipv6":
+		return nil
+	},
+	"ipv6": func(val string) error {
 		if !govalidator.IsIPv6(val) {
 			return fmt.Errorf("%q is not a valid IPv6 address", val)
 		}
-	case "uri":
-		u, err := url.Parse(val)
-		if err != nil {
-			return err
+		return nil
+	},
+	"uri": func(val string) error {
+		return checkURI(val, false, false)
+	},
+	"uri-reference": func(val string) error {
+		return checkURI(val, false, true)
+	},
+	"iri": func(val string) error {
+		return checkURI(val, true, false)
+	},
+	"iri-reference": func(val string) error {
+		return checkURI(val, true, true)
+	},
+	"idn-hostname": checkIDNHostname,
+	"idn-email":    checkIDNEmail,
+	"uuid": func(val string) error {
+		if !uuidRe.MatchString(val) {
+			return fmt.Errorf("%q is not a valid UUID", val)
+		}
+		return nil
+	},
+	"duration": func(val string) error {
+		if !durationRe.MatchString(val) {
+			return fmt.Errorf("%q is not a valid duration", val)
+		}
+		return nil
+	},
+	"uri-template": func(val string) error {
+		if !uriTemplateRe.MatchString(val) {
+			return fmt.Errorf("%q is not a valid URI template", val)
+		}
+		return nil
+	},
+	"date": func(val string) error {
+		if _, err := time.Parse("2006-01-02", val); err != nil {
+			return fmt.Errorf("%q is not a valid date", val)
+		}
+		return nil
+	},
+	"time": func(val string) error {
+		if !timeRe.MatchString(val) {
+			return fmt.Errorf("%q is not a valid time", val)
+		}
+		// time.Parse does the range checks for hours, minutes and seconds.
+		if _, err := time.Parse("15:04:05.999999999Z07:00", strings.ToUpper(val)); err != nil {
+			return fmt.Errorf("%q is not a valid time", val)
+		}
+		return nil
+	},
+	"json-pointer": func(val string) error {
+		if !jsonPointerRe.MatchString(val) {
+			return fmt.Errorf("%q is not a valid JSON pointer", val)
+		}
+		return nil
+	},
+	"relative-json-pointer": func(val string) error {
+		if !relativeJSONPointerRe.MatchString(val) {
+			return fmt.Errorf("%q is not a valid relative JSON pointer", val)
+		}
+		return nil
+	},
+	"regex": func(val string) error {
+		if _, err := regexp.Compile(val); err != nil {
+			return fmt.Errorf("%q is not a valid regexp: %s", val, err)
+		}
+		return nil
+	},
+}
+
+// checkURI checks URIs (RFC 3986) and IRIs (RFC 3987), which can have
+// non-ASCII characters. References can be relative.
+func checkURI(val string, iri bool, reference bool) error {
+	what := "URI"
+	if iri {
+		what = "IRI"
+	}
+	if reference {
+		what += " reference"
+	}
+	for i, c := range val {
+		if c < 0x20 || c == 0x7f || strings.ContainsRune(uriForbiddenChars, c) || (c >= utf8.RuneSelf && !iri) {
+			return fmt.Errorf("%q is not a valid %s: character %q is not allowed", val, what, c)
+		}
+		if c == '%' && (i+2 >= len(val) || !isHex(val[i+1]) || !isHex(val[i+2])) {
+			return fmt.Errorf("%q is not a valid %s: invalid percent-encoding", val, what)
+		}
+	}
+	u, err := url.Parse(val)
+	if err != nil {
+		return fmt.Errorf("%q is not a valid %s: %s", val, what, err)
+	}
+	if !reference && !u.IsAbs() {
+		return fmt.Errorf("%q is not absolute", val)
+	}
+	// url.Parse takes everything after the last colon for the port.
+	if !strings.HasPrefix(u.Host, "[") && strings.Count(u.Host, ":") > 1 {
+		return fmt.Errorf("%q is not a valid %s: IPv6 addresses need to be in brackets", val, what)
+	}
+	return nil
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// idnaDisallowed are the exceptions from RFC 5892, section 2.6, that are not
+// allowed in hostnames.
+var idnaDisallowed = map[rune]bool{
+	0x0640: true, 0x07FA: true, 0x302E: true, 0x302F: true,
+	0x3031: true, 0x3032: true, 0x3033: true, 0x3034: true, 0x3035: true, 0x303B: true,
+}
+
+func checkIDNHostname(val string) error {
+	ascii, err := idnaProfile.ToASCII(val)
+	if err != nil {
+		return fmt.Errorf("%q is not a valid internationalized hostname: %s", val, err)
+	}
+	if !hostnameRe.MatchString(ascii) {
+		return fmt.Errorf("%q is not a valid internationalized hostname", val)
+	}
+	// Labels may be in either form, contextual rules apply to the Unicode
+	// one.
+	unicodeName, err := idnaProfile.ToUnicode(ascii)
+	if err != nil {
+		return fmt.Errorf("%q is not a valid internationalized hostname: %s", val, err)
+	}
+	for _, label := range strings.Split(unicodeName, ".") {
+		if err := checkContextO(label); err != nil {
+			return fmt.Errorf("%q is not a valid internationalized hostname: %s", val, err)
+		}
+	}
+	return nil
+}
+
+// checkContextO checks the CONTEXTO rules and the disallowed exceptions from
+// RFC 5892, appendix A.
+func checkContextO(label string) error {
+	runes := []rune(label)
+	arabicIndic, extendedArabicIndic := false, false
+	for i, c := range runes {
+		if idnaDisallowed[c] {
+			return fmt.Errorf("character %q is not allowed", c)
+		}
+		before, after := rune(0), rune(0)
+		if i > 0 {
+			before = runes[i-1]
 		}
-		// XXX: \noideadog{this makes all the tests pass, not sure how it really should be validated}
-		if u.Host == "" {
-			return fmt.Errorf("%q is not absolute", val)
+		if i+1 < len(runes) {
+			after = runes[i+1]
 		}
+		switch {
+		case c == 0x00B7: // MIDDLE DOT
+			if before != 'l' || after != 'l' {
+				return fmt.Errorf("%q must be between two 'l'", c)
+			}
+		case c == 0x0375: // GREEK LOWER NUMERAL SIGN (KERAIA)
+			if !unicode.Is(unicode.Greek, after) {
+				return fmt.Errorf("%q must be followed by a Greek character", c)
+			}
+		case c == 0x05F3 || c == 0x05F4: // HEBREW PUNCTUATION GERESH and GERSHAYIM
+			if !unicode.Is(unicode.Hebrew, before) {
+				return fmt.Errorf("%q must follow a Hebrew character", c)
+			}
+		case c == 0x30FB: // KATAKANA MIDDLE DOT
+			found := false
+			for _, r := range runes {
+				if r != 0x30FB && (unicode.In(r, unicode.Hiragana, unicode.Katakana, unicode.Han)) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("%q requires a Hiragana, Katakana or Han character in the label", c)
+			}
+		case 0x0660 <= c && c <= 0x0669:
+			arabicIndic = true
+		case 0x06F0 <= c && c <= 0x06F9:
+			extendedArabicIndic = true
+		}
+	}
+	if arabicIndic && extendedArabicIndic {
+		return fmt.Errorf("Arabic-Indic and Extended Arabic-Indic digits can't be mixed")
 	}
 	return nil
 }
+
+// checkIDNEmail checks email addresses that can have non-ASCII characters
+// both in the local part (RFC 6531) and in the domain.
+func checkIDNEmail(val string) error {
+	i := strings.LastIndex(val, "@")
+	if i < 1 {
+		return fmt.Errorf("%q is not a valid email", val)
+	}
+	if err := checkIDNHostname(val[i+1:]); err != nil {
+		return fmt.Errorf("%q is not a valid email: %s", val, err)
+	}
+	domain, _ := idnaProfile.ToASCII(val[i+1:])
+	// Non-ASCII characters are allowed anywhere in the local part where
+	// letters are.
+	local := strings.Map(func(r rune) rune {
+		if r >= utf8.RuneSelf {
+			return 'a'
+		}
+		return r
+	}, val[:i])
+	if !govalidator.IsEmail(local + "@" + domain) {
+		return fmt.Errorf("%q is not a valid email", val)
+	}
+	return nil
+}
+
+// WithFormat makes NewValidator use check for "format": name. It can also
+// replace the built-in checkers. check returns an error describing the problem
+// if the value does not conform to the format. It is called concurrently if
+// the Validator is used concurrently. Since 2019-09 "format" is only
+// an annotation, so check is not used unless the meta-schema of the schema
+// asks for the format-assertion vocabulary or AssertFormats is used.
+func WithFormat(name string, check func(val string) error) Option {
+	return func(o *options) {
+		if o.formats == nil {
+			o.formats = map[string]func(string) error{}
+		}
+		o.formats[name] = check
+	}
+}
+
+// AssertFormats makes NewValidator check "format" in the dialects where it is
+// only an annotation, 2019-09 and later, as if the meta-schema asked for the
+// format-assertion vocabulary.
+func AssertFormats() Option {
+	return func(o *options) {
+		o.assertFormats = true
+	}
+}
+
+// UnknownFormats sets what NewValidator does about schemas with "format" that
+// is neither built in nor added with WithFormat. handle is called for each of
+// them with the location of the keyword and the name of the format. If it
+// returns an error, NewValidator fails with it, otherwise the format is
+// ignored, so handle can be used to log a warning. Without this option such
+// formats are silently ignored.
+func UnknownFormats(handle func(path string, format string) error) Option {
+	return func(o *options) {
+		o.unknownFormat = handle
+	}
+}
+
+// RejectUnknownFormats is a handler for UnknownFormats that makes NewValidator
+// fail.
+func RejectUnknownFormats(path string, format string) error {
+	return fmt.Errorf("%q: unknown format %q", path, format)
+}
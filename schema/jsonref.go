@@ -44,6 +44,12 @@ func unescapeRefToken(s string) (string, error) {
 	return b.String(), nil
 }
 
+func escapeRefToken(s string) string {
+	return refTokenEscaper.Replace(s)
+}
+
+var refTokenEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
 func resolveRef(v json.Value, ref string) (json.Value, error) {
 	if ref == "" {
 		return v, nil
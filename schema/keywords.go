@@ -0,0 +1,128 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+
+	json "github.com/cesanta/ucl"
+)
+
+// Keyword is a custom keyword added with WithKeyword.
+type Keyword interface {
+	// Compile is called by NewValidator for every schema that has the
+	// keyword, with the value of the keyword. It returns an error if the
+	// value is not valid, which makes NewValidator fail. Otherwise the result
+	// is kept along with the compiled schema and used every time a value is
+	// validated against it.
+	Compile(value json.Value) (CompiledKeyword, error)
+}
+
+// CompiledKeyword is a custom keyword compiled for a particular schema. It
+// must be safe for concurrent use if the Validator is used concurrently.
+type CompiledKeyword interface {
+	// Validate checks val, which is the value at c.InstancePath. Violations
+	// and annotations are reported with c.
+	Validate(c *KeywordContext, val json.Value)
+}
+
+// WithKeyword makes NewValidator use k for keyword name. It can't be one of
+// the keywords of the known dialects. Unlike other keywords starting with "x-",
+// custom keywords are not collected as annotations by Annotate, but they can
+// add annotations on their own.
+func WithKeyword(name string, k Keyword) Option {
+	return func(o *options) {
+		if o.keywords == nil {
+			o.keywords = map[string]Keyword{}
+		}
+		o.keywords[name] = k
+	}
+}
+
+// KeywordContext is passed to CompiledKeyword.Validate.
+type KeywordContext struct {
+	// InstancePath is a JSON Pointer to the value being validated.
+	InstancePath string
+	// Value is the value of the keyword in the schema.
+	Value json.Value
+
+	keyword string
+	node    *node
+	s       *state
+	// err is set if validation needs to stop.
+	err error
+}
+
+// Error reports a violation. params become ValidationError.Params, and the
+// message is formatted with fmt.Sprintf.
+func (c *KeywordContext) Error(params map[string]interface{}, format string, args ...interface{}) {
+	if c.err != nil {
+		return
+	}
+	c.err = c.s.errs.add(newValidationError(c.InstancePath, c.node.path, c.keyword, params, format, args...))
+}
+
+// Annotate adds an annotation for the value, which is collected by Annotate
+// if the value is valid against the schema.
+func (c *KeywordContext) Annotate(value json.Value) {
+	if !c.s.annotate {
+		return
+	}
+	c.s.annotations = append(c.s.annotations, &Annotation{
+		InstancePath: c.InstancePath,
+		SchemaPath:   c.node.path + "/" + escapeRefToken(c.keyword),
+		Keyword:      c.keyword,
+		Value:        value,
+	})
+}
+
+type customKeyword struct {
+	name     string
+	value    json.Value
+	compiled CompiledKeyword
+}
+
+// checkKeywords returns an error if any of the custom keywords is one of the
+// standard ones.
+func checkKeywords(keywords map[string]Keyword) error {
+	for name := range keywords {
+		for _, d := range dialects {
+			if d.knows(name) {
+				return fmt.Errorf("custom keyword %q is a %s keyword", name, d)
+			}
+		}
+	}
+	return nil
+}
+
+// compileCustom compiles the custom keywords present in the schema.
+func (c *compiler) compileCustom(n *node, schema *json.Object) error {
+	names := make([]string, 0, len(c.keywords))
+	for name := range c.keywords {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		x, found := schema.Lookup(name)
+		if !found {
+			continue
+		}
+		k, err := c.keywords[name].Compile(x)
+		if err != nil {
+			return fmt.Errorf("%q is not valid: %s", n.path+"/"+escapeRefToken(name), err)
+		}
+		n.custom = append(n.custom, customKeyword{name: name, value: x, compiled: k})
+	}
+	return nil
+}
+
+// validateCustom checks val against the custom keywords of n.
+func (s *state) validateCustom(n *node, path string, val json.Value) error {
+	for _, k := range n.custom {
+		c := &KeywordContext{InstancePath: path, Value: k.value, keyword: k.name, node: n, s: s}
+		k.compiled.Validate(c, val)
+		if c.err != nil {
+			return c.err
+		}
+	}
+	return nil
+}
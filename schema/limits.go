@@ -0,0 +1,187 @@
+package schema
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	json "github.com/cesanta/ucl"
+)
+
+// Limits bound the work ValidateContext does for a single value, which is
+// useful for values that come from untrusted sources. Zero means no limit.
+type Limits struct {
+	// MaxDepth is how deep into the value validation can go: 1 allows the
+	// items and properties of the value, but not their own items and
+	// properties.
+	MaxDepth int
+	// MaxEvaluations is how many keywords can be evaluated against the value
+	// and its parts, including the ones in subschemas applied only to see if
+	// they match, like the ones in "anyOf". Every property of a schema object
+	// applied to a value counts once, except for "enum", which counts once
+	// for every value in it, and "uniqueItems", which counts once for every
+	// pair of items it may need to compare. Boolean schemas count once too.
+	// The evaluations are counted before they happen, so a schema that would
+	// exceed the limit is not evaluated at all.
+	MaxEvaluations int
+	// MaxErrors is how many violations can be collected.
+	MaxErrors int
+}
+
+// WithLimits sets the limits used by ValidateContext. Other methods of the
+// Validator are not limited.
+func WithLimits(l Limits) Option {
+	return func(o *options) {
+		o.limits = l
+	}
+}
+
+var (
+	// ErrMaxDepth means that the value is nested deeper than Limits.MaxDepth,
+	// or than ValidateReader allows.
+	ErrMaxDepth = errors.New("value is nested too deeply")
+	// ErrMaxEvaluations means that validation needed more than
+	// Limits.MaxEvaluations evaluations.
+	ErrMaxEvaluations = errors.New("too many schema evaluations")
+	// ErrMaxErrors means that the value has at least Limits.MaxErrors
+	// violations.
+	ErrMaxErrors = errors.New("too many violations")
+)
+
+// IncompleteError is returned by ValidateContext if validation had to stop
+// before it was done, so it is not known whether the value is valid.
+type IncompleteError struct {
+	// Err is why validation stopped: ErrMaxDepth, ErrMaxEvaluations,
+	// ErrMaxErrors or the error of the context.
+	Err error
+	// Errors are the violations found before that.
+	Errors Errors
+}
+
+func (e *IncompleteError) Error() string {
+	return fmt.Sprintf("validation stopped after finding %d violations: %s", len(e.Errors), e.Err)
+}
+
+// Unwrap returns Err, so errors.Is(err, context.DeadlineExceeded) and the like
+// work.
+func (e *IncompleteError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateContext is like ValidateAll, but stops once ctx is done or one of
+// the limits set with WithLimits is exceeded, returning *IncompleteError.
+func (v *Validator) ValidateContext(ctx context.Context, val json.Value) error {
+	if err := ctx.Err(); err != nil {
+		return &IncompleteError{Err: err}
+	}
+	s := v.limitedState(ctx)
+	return s.limitedResult(s.validate(v.root, "", val))
+}
+
+// limitedState returns a state for validating a value within the limits of v
+// and until ctx is done.
+func (v *Validator) limitedState(ctx context.Context) *state {
+	s := v.newState(v.limits.MaxErrors)
+	s.limiter = &limiter{ctx: ctx, limits: v.limits}
+	s.errs.limiter = s.limiter
+	return s
+}
+
+// limitedResult returns the result of validation with a state made by
+// limitedState that returned err.
+func (s *state) limitedResult(err error) error {
+	if err == errTooManyErrors {
+		s.limiter.halt(ErrMaxErrors)
+	}
+	if stop := s.limiter.stopped(); stop != nil {
+		return &IncompleteError{Err: stop, Errors: Errors(s.errs.errs)}
+	}
+	if err != nil && err != errTooManyErrors {
+		return err
+	}
+	if len(s.errs.errs) > 0 {
+		return Errors(s.errs.errs)
+	}
+	return nil
+}
+
+// limiter enforces the limits of ValidateContext. It is shared by all the
+// goroutines validating the value, see Parallel.
+type limiter struct {
+	ctx         context.Context
+	limits      Limits
+	evaluations int64 // Accessed atomically.
+
+	// stop is the reason validation had to stop, if it had to. halted is set
+	// along with it, so that it can be checked without locking mu.
+	halted int32
+	mu     sync.Mutex
+	stop   error
+}
+
+// ctxCheckInterval is how many evaluations happen between checks of the
+// context.
+const ctxCheckInterval = 64
+
+// tick counts cost evaluations of keywords against a value at depth, see
+// state.depth, and returns a non-nil error if validation needs to stop. Once
+// it does, it keeps returning the same error, since errors returned while
+// validating in isolation, like in "anyOf", are not propagated.
+func (l *limiter) tick(cost int64, depth int) error {
+	if err := l.stopped(); err != nil {
+		return err
+	}
+	n := atomic.AddInt64(&l.evaluations, cost)
+	switch {
+	case l.limits.MaxEvaluations > 0 && n > int64(l.limits.MaxEvaluations):
+		return l.halt(ErrMaxEvaluations)
+	case l.limits.MaxDepth > 0 && depth > l.limits.MaxDepth:
+		return l.halt(ErrMaxDepth)
+	case n/ctxCheckInterval != (n-cost)/ctxCheckInterval:
+		if err := l.ctx.Err(); err != nil {
+			return l.halt(err)
+		}
+	}
+	return nil
+}
+
+// cost returns the number of keyword evaluations it takes to apply n to val,
+// see Limits.MaxEvaluations.
+func (n *node) cost(val json.Value) int64 {
+	cost := int64(n.keywords)
+	if cost == 0 {
+		cost = 1
+	}
+	if n.enum != nil && len(n.enum.Value) > 1 {
+		cost += int64(len(n.enum.Value) - 1)
+	}
+	if a, ok := val.(*json.Array); ok && n.uniqueItems && len(a.Value) > 2 {
+		items := int64(len(a.Value))
+		cost += items*(items-1)/2 - 1
+	}
+	return cost
+}
+
+// stopped returns the reason validation had to stop, nil if it can go on.
+func (l *limiter) stopped() error {
+	if atomic.LoadInt32(&l.halted) == 0 {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stop
+}
+
+// halt stops validation because of err, unless it is already stopped, and
+// returns the reason it is stopped.
+func (l *limiter) halt(err error) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.stop == nil {
+		l.stop = err
+		atomic.StoreInt32(&l.halted, 1)
+	}
+	return l.stop
+}
@@ -4,52 +4,89 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 
 	json "github.com/cesanta/ucl"
 )
 
-// Loader is an entity used for fetching schemas by reference.
+// Loader is an entity used for fetching schemas by reference. It is safe for
+// concurrent use, so a single Loader can be shared by validators constructed
+// in different goroutines.
 type Loader struct {
+	mu             sync.Mutex
 	networkEnabled bool
 	cache          map[string]json.Value
+	// fetches are the schemas being fetched from the network, by id.
+	// Goroutines that need the same schema wait for the first fetch instead
+	// of doing their own.
+	fetches map[string]*fetch
+}
+
+type fetch struct {
+	done   chan struct{} // Closed once the fetch is over.
+	schema json.Value
+	err    error
 }
 
 // NewLoader creates a new Loader instance.
 func NewLoader() *Loader {
-	return &Loader{cache: map[string]json.Value{}}
+	return &Loader{cache: map[string]json.Value{}, fetches: map[string]*fetch{}}
 }
 
 // Get returns a schema identified with id.
 func (l *Loader) Get(id string) (json.Value, error) {
-	s, found := l.cache[id]
-	if found {
+	l.mu.Lock()
+	if s, found := l.cache[id]; found {
+		l.mu.Unlock()
 		return s, nil
 	}
-	if l.networkEnabled {
-		resp, err := http.Get(id)
-		if err != nil {
-			return nil, err
-		}
-		s, err := json.Parse(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			return nil, err
-		}
-		l.AddAs(s, id)
-		return s, nil
+	if !l.networkEnabled {
+		l.mu.Unlock()
+		return nil, fmt.Errorf("schema %q is not present in the cache and fetching is disabled", id)
+	}
+	f, found := l.fetches[id]
+	if found {
+		l.mu.Unlock()
+		<-f.done
+		return f.schema, f.err
+	}
+	f = &fetch{done: make(chan struct{})}
+	l.fetches[id] = f
+	l.mu.Unlock()
+
+	f.schema, f.err = fetchSchema(id)
+	l.mu.Lock()
+	delete(l.fetches, id)
+	if f.err == nil {
+		l.add(f.schema, id)
+	}
+	l.mu.Unlock()
+	close(f.done)
+	return f.schema, f.err
+}
+
+func fetchSchema(url string) (json.Value, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
 	}
-	return nil, fmt.Errorf("schema %q is not present in the cache and fetching is disabled", id)
+	defer resp.Body.Close()
+	return Parse(resp.Body)
 }
 
-// Add adds schema to the cache. Schema must have 'id' property.
+// Add adds schema to the cache. Schema must have 'id' property, or '$id' since
+// draft 06.
 func (l *Loader) Add(schema json.Value) error {
 	s, ok := schema.(*json.Object)
 	if !ok {
 		return fmt.Errorf("schema must be an object")
 	}
-	id, ok := s.Find("id").(*json.String)
+	id, ok := s.Find("$id").(*json.String)
+	if !ok {
+		id, ok = s.Find("id").(*json.String)
+	}
 	if !ok {
-		return fmt.Errorf("schema must have string property \"id\"")
+		return fmt.Errorf("schema must have string property \"id\" or \"$id\"")
 	}
 	return l.AddAs(schema, id.Value)
 }
@@ -66,12 +103,24 @@ func (l *Loader) AddAs(schema json.Value, id string) error {
 	if id == "" {
 		return fmt.Errorf("cannot add a schema with empty id")
 	}
-	l.cache[id] = schema
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.add(schema, id)
 	return nil
 }
 
+// add puts schema into the cache. l.mu must be held.
+func (l *Loader) add(schema json.Value, id string) {
+	if i := strings.Index(id, "#"); i >= 0 {
+		id = id[:i]
+	}
+	l.cache[id] = schema
+}
+
 // EnableNetworkAccess enables or disables fetching schemas not present in the
 // cache from the Internet. Use with caution.
 func (l *Loader) EnableNetworkAccess(enable bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	l.networkEnabled = enable
 }
@@ -0,0 +1,254 @@
+package schema
+
+import (
+	"strings"
+
+	json "github.com/cesanta/ucl"
+)
+
+// Output is the top level of the "flag" and "basic" output formats defined by
+// the JSON Schema specification. Both are meant to be serialized as JSON.
+type Output struct {
+	Valid bool `json:"valid"`
+	// Errors is a flat list of violations, always empty in the "flag"
+	// format.
+	Errors []*OutputUnit `json:"errors,omitempty"`
+}
+
+// OutputUnit describes the result of applying a schema or a keyword to a
+// value, see Validator.DetailedOutput.
+type OutputUnit struct {
+	Valid bool `json:"valid"`
+	// KeywordLocation is a JSON Pointer to the schema or keyword, following
+	// the path taken during validation, including "$ref"s, e.g.
+	// "/properties/foo/$ref/maxLength".
+	KeywordLocation string `json:"keywordLocation"`
+	// AbsoluteKeywordLocation is the location of the schema or keyword after
+	// following "$ref"s, like ValidationError.SchemaPath. It is only set if it
+	// is different from KeywordLocation.
+	AbsoluteKeywordLocation string `json:"absoluteKeywordLocation,omitempty"`
+	// InstanceLocation is a JSON Pointer to the value, "" being the whole
+	// value.
+	InstanceLocation string `json:"instanceLocation"`
+	// Error is the message of a failed keyword, or of a schema that is
+	// invalid on its own, like false.
+	Error string `json:"error,omitempty"`
+	// Errors are the results of nested schemas and failed keywords. Before
+	// they are passed to the caller, units of valid schemas are removed from
+	// them, unless the format is "verbose".
+	Errors []*OutputUnit `json:"errors,omitempty"`
+}
+
+// FlagOutput validates val and returns the result in the "flag" format, which
+// only says whether val is valid.
+func (v *Validator) FlagOutput(val json.Value) *Output {
+	return &Output{Valid: v.Validate(val) == nil}
+}
+
+// BasicOutput validates val and returns the result in the "basic" format: a
+// flat list of all the failed keywords.
+func (v *Validator) BasicOutput(val json.Value) *Output {
+	u := v.output(val, false)
+	return &Output{Valid: u.Valid, Errors: u.failures(nil)}
+}
+
+// DetailedOutput validates val and returns the result in the "detailed"
+// format: a hierarchy of units that follows the structure of the schema, with
+// only the failed schemas and keywords in it. Schemas with a single failed
+// subschema or keyword are replaced by it, except for the root schema.
+func (v *Validator) DetailedOutput(val json.Value) *OutputUnit {
+	u := v.output(val, false)
+	for i, e := range u.Errors {
+		u.Errors[i] = e.collapse()
+	}
+	return u
+}
+
+// VerboseOutput validates val and returns the result in the "verbose" format:
+// like "detailed", but with all the schemas applied to the value, including
+// the valid ones and the ones applied only to see if the value is valid, like
+// "if" or "not".
+func (v *Validator) VerboseOutput(val json.Value) *OutputUnit {
+	return v.output(val, true)
+}
+
+// output validates val collecting all the violations and returns the unit of
+// the root schema.
+func (v *Validator) output(val json.Value, verbose bool) *OutputUnit {
+	s := v.newState(0)
+	top := &unitBuilder{}
+	s.out, s.verbose = top, verbose
+	s.validate(v.root, "", val)
+	return top.children[0].unit
+}
+
+// failures returns the units with errors in u and below it, appended to r.
+func (u *OutputUnit) failures(r []*OutputUnit) []*OutputUnit {
+	if u.Error != "" {
+		r = append(r, &OutputUnit{
+			KeywordLocation:         u.KeywordLocation,
+			AbsoluteKeywordLocation: u.AbsoluteKeywordLocation,
+			InstanceLocation:        u.InstanceLocation,
+			Error:                   u.Error,
+		})
+	}
+	for _, e := range u.Errors {
+		r = e.failures(r)
+	}
+	return r
+}
+
+// collapse replaces units that have no error of their own and a single nested
+// one with the nested unit.
+func (u *OutputUnit) collapse() *OutputUnit {
+	for i, e := range u.Errors {
+		u.Errors[i] = e.collapse()
+	}
+	if u.Error == "" && len(u.Errors) == 1 {
+		return u.Errors[0]
+	}
+	return u
+}
+
+// unitBuilder collects the output unit of a schema while it is applied.
+type unitBuilder struct {
+	unit *OutputUnit
+	node *node
+	// start is the number of violations recorded before the schema was
+	// applied. Violations recorded after that belong either to the schema
+	// itself or to one of the children.
+	start    int
+	children []childUnit
+}
+
+type childUnit struct {
+	unit *OutputUnit
+	// Violations with indices in [start, end) were recorded by the child.
+	// For isolated children both are the number of violations of the parent
+	// at the moment the child was applied, since they have their own list.
+	start, end int
+	// isolated is set for subschemas applied with state.isolate. They only
+	// become part of the output if the keyword that applied them fails, or in
+	// the "verbose" format.
+	isolated bool
+}
+
+// enter starts building the unit of n, which is applied to the value at path,
+// and returns the function to call once n is done.
+func (s *state) enter(n *node, path string) func() {
+	parent := s.out
+	kw := ""
+	if parent.node != nil {
+		kw = parent.unit.KeywordLocation + parent.node.keywordTo(n, s.via)
+	}
+	b := &unitBuilder{
+		unit:  &OutputUnit{KeywordLocation: kw, InstanceLocation: path},
+		node:  n,
+		start: len(s.errs.errs),
+	}
+	if n.path != "#"+kw {
+		b.unit.AbsoluteKeywordLocation = n.path
+	}
+	child := childUnit{unit: b.unit, start: b.start, isolated: s.isolated}
+	if s.isolated {
+		child.start = s.isolatedAt
+	}
+	s.out, s.via, s.isolated = b, "", false
+	return func() {
+		s.leave(b)
+		s.out = parent
+		child.end = len(s.errs.errs)
+		if child.isolated {
+			child.end = child.start
+		}
+		parent.children = append(parent.children, child)
+	}
+}
+
+// keywordTo returns the location of sub relative to n. via is the reference
+// keyword sub was reached with, or "" if sub is one of the subschemas of n.
+func (n *node) keywordTo(sub *node, via string) string {
+	if via != "" {
+		return "/" + via
+	}
+	// Subschemas are always compiled at locations nested in their parent.
+	return strings.TrimPrefix(sub.path, n.path)
+}
+
+// leave finishes the unit of b.node, once everything in it is applied.
+func (s *state) leave(b *unitBuilder) {
+	u := b.unit
+	errs := s.errs.errs
+	u.Valid = len(errs) == b.start
+	isolated := map[*OutputUnit]bool{}
+	// leaves adds units for the violations with indices in [from, to), which
+	// are recorded by the schema itself.
+	leaves := func(from, to int) {
+		for _, err := range errs[from:to] {
+			ve, ok := err.(*ValidationError)
+			if !ok {
+				continue
+			}
+			if ve.SchemaPath == b.node.path {
+				// The schema is false.
+				u.Error = ve.Message
+				continue
+			}
+			keyword := strings.TrimPrefix(ve.SchemaPath, b.node.path)
+			leaf := &OutputUnit{
+				KeywordLocation:  u.KeywordLocation + keyword,
+				InstanceLocation: ve.InstancePath,
+				Error:            ve.Message,
+			}
+			if ve.SchemaPath != "#"+leaf.KeywordLocation {
+				leaf.AbsoluteKeywordLocation = ve.SchemaPath
+			}
+			// Subschemas applied in isolation by the keyword explain why it
+			// failed.
+			prefix := u.KeywordLocation + "/" + subschemaKeyword(ve.Keyword)
+			rest := u.Errors[:0]
+			for _, e := range u.Errors {
+				if isolated[e] && (e.KeywordLocation == prefix || strings.HasPrefix(e.KeywordLocation, prefix+"/")) {
+					delete(isolated, e)
+					if s.verbose || !e.Valid {
+						leaf.Errors = append(leaf.Errors, e)
+					}
+				} else {
+					rest = append(rest, e)
+				}
+			}
+			u.Errors = append(rest, leaf)
+		}
+	}
+	next := b.start
+	for _, c := range b.children {
+		leaves(next, c.start)
+		u.Errors = append(u.Errors, c.unit)
+		if c.isolated {
+			isolated[c.unit] = true
+		}
+		next = c.end
+	}
+	leaves(next, len(errs))
+
+	if s.verbose {
+		return
+	}
+	rest := u.Errors[:0]
+	for _, e := range u.Errors {
+		if !e.Valid && !isolated[e] {
+			rest = append(rest, e)
+		}
+	}
+	u.Errors = rest
+}
+
+// subschemaKeyword returns the keyword with the subschemas that are checked by
+// keyword.
+func subschemaKeyword(keyword string) string {
+	switch keyword {
+	case "minContains", "maxContains":
+		return "contains"
+	}
+	return keyword
+}
@@ -0,0 +1,118 @@
+package schema
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Parallel makes the Validator split the items of large arrays and the
+// properties of large objects between up to workers goroutines, including
+// the one that calls the Validator, for every value it validates. Nested
+// arrays and objects share the same goroutines. Violations are reported in
+// the same order as without this option. Output formats and Annotate don't
+// use more than one goroutine.
+func Parallel(workers int) Option {
+	return func(o *options) {
+		o.workers = workers
+	}
+}
+
+// minParallel is the smallest number of items or properties that are worth
+// splitting between goroutines.
+const minParallel = 16
+
+// forEach calls job for every i from 0 to count-1, with the state to use for
+// it, and returns the first non-nil error it returns. With Parallel the jobs
+// run concurrently, each with its own state, and once they are done the
+// violations are recorded in order of i, as if the jobs ran one after
+// another.
+func (s *state) forEach(count int, job func(s *state, i int) error) error {
+	if s.workers == nil || count < minParallel || s.out != nil || s.annotate {
+		for i := 0; i < count; i++ {
+			if err := job(s, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// Every job can record as many violations as there is room for, and
+	// the ones after a job that filled it all up are not needed.
+	room := 0
+	if s.errs.max > 0 {
+		room = s.errs.max - len(s.errs.errs)
+	}
+	states := make([]*state, count)
+	errs := make([]error, count)
+	next, cutoff := int64(-1), int64(count)
+	work := func() {
+		for {
+			i := atomic.AddInt64(&next, 1)
+			if i >= int64(count) || i > atomic.LoadInt64(&cutoff) {
+				return
+			}
+			w := s.fork(room)
+			states[i], errs[i] = w, job(w, int(i))
+			if errs[i] == nil {
+				continue
+			}
+			for c := atomic.LoadInt64(&cutoff); i < c && !atomic.CompareAndSwapInt64(&cutoff, c, i); {
+				c = atomic.LoadInt64(&cutoff)
+			}
+		}
+	}
+	var wg sync.WaitGroup
+spawn:
+	for started := 1; started < count; started++ {
+		select {
+		case s.workers <- struct{}{}:
+			wg.Add(1)
+			go func() {
+				defer func() {
+					<-s.workers
+					wg.Done()
+				}()
+				work()
+			}()
+		default:
+			// The other goroutines are busy.
+			break spawn
+		}
+	}
+	work()
+	wg.Wait()
+
+	// All the jobs up to the cutoff are done.
+	done := count
+	if c := int(cutoff); c < count {
+		done = c + 1
+	}
+	for i, w := range states[:done] {
+		for _, err := range w.errs.errs {
+			if err := s.errs.add(err); err != nil {
+				return err
+			}
+		}
+		if errs[i] != nil {
+			return errs[i]
+		}
+	}
+	return nil
+}
+
+// fork returns a state for validating a part of the current value in another
+// goroutine, which records up to maxErrors violations, 0 meaning no limit.
+func (s *state) fork(maxErrors int) *state {
+	w := &state{
+		errs:     errorList{max: maxErrors, limiter: s.errs.limiter},
+		scope:    append([]*node(nil), s.scope...),
+		defaults: s.defaults,
+		coerce:   s.coerce,
+		limiter:  s.limiter,
+		depth:    s.depth,
+		workers:  s.workers,
+	}
+	if s.evaluated != nil {
+		w.evaluated = &evaluated{}
+	}
+	return w
+}
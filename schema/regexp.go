@@ -0,0 +1,63 @@
+package schema
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Regexp is a compiled regular expression, used for "pattern",
+// "patternProperties" and "format": "regex".
+type Regexp interface {
+	// MatchString reports whether s contains a match. It returns an error if
+	// it could not find out, e.g. because matching took too long.
+	MatchString(s string) (bool, error)
+	// String returns the source of the regular expression.
+	String() string
+}
+
+// RegexpEngine compiles regular expressions, see WithRegexpEngine. Compiled
+// expressions are used concurrently if the Validator is.
+type RegexpEngine interface {
+	Compile(expr string) (Regexp, error)
+}
+
+// RE2 is the default RegexpEngine. It uses the regexp package, which is fast
+// and never takes long to match, but its syntax is not quite the ECMA-262 one
+// required by the JSON Schema specification: lookarounds and backreferences
+// are not supported, and \d, \w and \s have slightly different meanings.
+var RE2 RegexpEngine = re2Engine{}
+
+type re2Engine struct{}
+
+func (re2Engine) Compile(expr string) (Regexp, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return re2Regexp{re}, nil
+}
+
+type re2Regexp struct {
+	*regexp.Regexp
+}
+
+func (re re2Regexp) MatchString(s string) (bool, error) {
+	return re.Regexp.MatchString(s), nil
+}
+
+// WithRegexpEngine makes NewValidator compile regular expressions in the
+// schema with e instead of RE2. Patterns that e can't compile make
+// NewValidator fail.
+func WithRegexpEngine(e RegexpEngine) Option {
+	return func(o *options) {
+		o.regexps = e
+	}
+}
+
+// checkRegexp checks "format": "regex" with the engine of the compiler.
+func (c *compiler) checkRegexp(val string) error {
+	if _, err := c.regexps.Compile(val); err != nil {
+		return fmt.Errorf("%q is not a valid regexp: %s", val, err)
+	}
+	return nil
+}
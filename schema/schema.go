@@ -8,6 +8,11 @@ import (
 	json "github.com/cesanta/ucl"
 )
 
+var (
+	anchorRe201909 = regexp.MustCompile(`^[A-Za-z][-A-Za-z0-9.:_]*$`)
+	anchorRe202012 = regexp.MustCompile(`^[A-Za-z_][-A-Za-z0-9._]*$`)
+)
+
 var validType = map[string]bool{
 	"array":   true,
 	"boolean": true,
@@ -20,76 +25,81 @@ var validType = map[string]bool{
 
 // ValidateDraft04Schema checks that v is a valid JSON schema.
 func ValidateDraft04Schema(v json.Value) error {
-	return validateDraft04Schema("#", v)
+	return Draft04.validateSchema("#", v)
+}
+
+// ValidateDraft06Schema checks that v is a valid draft 06 JSON schema.
+func ValidateDraft06Schema(v json.Value) error {
+	return Draft06.validateSchema("#", v)
+}
+
+// ValidateDraft07Schema checks that v is a valid draft 07 JSON schema.
+func ValidateDraft07Schema(v json.Value) error {
+	return Draft07.validateSchema("#", v)
+}
+
+// ValidateDraft201909Schema checks that v is a valid 2019-09 JSON schema.
+func ValidateDraft201909Schema(v json.Value) error {
+	return Draft201909.validateSchema("#", v)
+}
+
+// ValidateDraft202012Schema checks that v is a valid 2020-12 JSON schema.
+func ValidateDraft202012Schema(v json.Value) error {
+	return Draft202012.validateSchema("#", v)
 }
 
-func validateDraft04Schema(path string, v json.Value) error {
+// keywordChecker checks the value of a single keyword in a schema.
+type keywordChecker func(d *Dialect, path string, v json.Value) error
+
+func (d *Dialect) validateSchema(path string, v json.Value) error {
 	switch v := v.(type) {
 	case *json.Object:
+		if e := embeddedDialect(v); e != nil && e != d && path != "#" {
+			return e.validateSchema(path, v)
+		}
 		s, found := v.Lookup("$ref")
 		if found {
-			return validateURI(path+"/$ref", s)
-		}
-		validators := map[string]func(string, json.Value) error{
-			"type":                 validateType,
-			"id":                   validateURI,
-			"$schema":              validateURI,
-			"title":                validateString,
-			"description":          validateString,
-			"multipleOf":           validateMultipleOf,
-			"maximum":              validateNumber,
-			"minimum":              validateNumber,
-			"exclusiveMaximum":     validateBoolean,
-			"exclusiveMinimum":     validateBoolean,
-			"minLength":            validatePositiveInteger,
-			"maxLength":            validatePositiveInteger,
-			"pattern":              validatePattern,
-			"additionalItems":      validateBoolOrSchema,
-			"items":                validateItems,
-			"maxItems":             validatePositiveInteger,
-			"minItems":             validatePositiveInteger,
-			"uniqueItems":          validateBoolean,
-			"maxProperties":        validatePositiveInteger,
-			"minProperties":        validatePositiveInteger,
-			"required":             validateStringArray,
-			"additionalProperties": validateBoolOrSchema,
-			"definitions":          validateSchemaCollection,
-			"properties":           validateSchemaCollection,
-			"patternProperties":    validateSchemaCollection,
-			"dependencies":         validateDependencies,
-			"enum":                 validateEnum,
-			"allOf":                validateSchemaArray,
-			"anyOf":                validateSchemaArray,
-			"oneOf":                validateSchemaArray,
-			"not":                  validateDraft04Schema,
+			if err := validateURI(d, path+"/$ref", s); err != nil || !d.refSiblings {
+				return err
+			}
 		}
-		for prop, validate := range validators {
+		for prop, validate := range d.keywords {
 			val, found := v.Lookup(prop)
 			if !found {
 				continue
 			}
-			err := validate(path+"/"+prop, val)
+			err := validate(d, path+"/"+prop, val)
 			if err != nil {
 				return err
 			}
 		}
-		_, a := v.Lookup("exclusiveMaximum")
-		_, b := v.Lookup("maximum")
-		if a && !b {
-			return fmt.Errorf("%q: \"exclusiveMaximum\" requires \"maximum\" to be present")
-		}
-		_, a = v.Lookup("exclusiveMinimum")
-		_, b = v.Lookup("minimum")
-		if a && !b {
-			return fmt.Errorf("%q: \"exclusiveMinimum\" requires \"minimum\" to be present")
+		if d.booleanExclusiveLimits {
+			_, a := v.Lookup("exclusiveMaximum")
+			_, b := v.Lookup("maximum")
+			if a && !b {
+				return fmt.Errorf("%q: \"exclusiveMaximum\" requires \"maximum\" to be present", path)
+			}
+			_, a = v.Lookup("exclusiveMinimum")
+			_, b = v.Lookup("minimum")
+			if a && !b {
+				return fmt.Errorf("%q: \"exclusiveMinimum\" requires \"minimum\" to be present", path)
+			}
 		}
 		return nil
+	case *json.Bool:
+		if d.booleanSchemas {
+			return nil
+		}
+		return fmt.Errorf("%q has invalid type, it needs to be an object", path)
 	default:
+		if d.booleanSchemas {
+			return fmt.Errorf("%q has invalid type, it needs to be an object or a boolean", path)
+		}
 		return fmt.Errorf("%q has invalid type, it needs to be an object", path)
 	}
 }
 
-func validateType(path string, v json.Value) error {
+func validateType(d *Dialect, path string, v json.Value) error {
 	switch v := v.(type) {
 	case *json.String:
 		if !validType[v.Value] {
@@ -123,7 +133,7 @@ func isValidURI(s string) error {
 	return err
 }
 
-func validateURI(path string, v json.Value) error {
+func validateURI(d *Dialect, path string, v json.Value) error {
 	s, ok := v.(*json.String)
 	if !ok {
 		return fmt.Errorf("%q must be a string", path)
@@ -134,7 +144,7 @@ func validateURI(path string, v json.Value) error {
 	return nil
 }
 
-func validateString(path string, v json.Value) error {
+func validateString(d *Dialect, path string, v json.Value) error {
 	_, ok := v.(*json.String)
 	if !ok {
 		return fmt.Errorf("%q must be a string", path)
@@ -142,18 +152,15 @@ func validateString(path string, v json.Value) error {
 	return nil
 }
 
-func validateNumber(path string, v json.Value) error {
-	_, ok := v.(*json.Number)
-	if !ok {
-		_, ok := v.(*json.Integer)
-		if !ok {
-			return fmt.Errorf("%q must be a number", path)
-		}
+func validateNumber(d *Dialect, path string, v json.Value) error {
+	switch v.(type) {
+	case *json.Number, *json.Integer, *Decimal:
+		return nil
 	}
-	return nil
+	return fmt.Errorf("%q must be a number", path)
 }
 
-func validateBoolean(path string, v json.Value) error {
+func validateBoolean(d *Dialect, path string, v json.Value) error {
 	_, ok := v.(*json.Bool)
 	if !ok {
 		return fmt.Errorf("%q must be a boolean", path)
@@ -161,7 +168,7 @@ func validateBoolean(path string, v json.Value) error {
 	return nil
 }
 
-func validateMultipleOf(path string, v json.Value) error {
+func validateMultipleOf(d *Dialect, path string, v json.Value) error {
 	switch n := v.(type) {
 	case *json.Number:
 		if n.Value <= 0 {
@@ -171,54 +178,54 @@ func validateMultipleOf(path string, v json.Value) error {
 		if n.Value <= 0 {
 			return fmt.Errorf("%q must be > 0", path)
 		}
+	case *Decimal:
+		if sign(n) <= 0 {
+			return fmt.Errorf("%q must be > 0", path)
+		}
 	default:
 		return fmt.Errorf("%q must be a number", path)
 	}
 	return nil
 }
 
-func validatePositiveInteger(path string, v json.Value) error {
+func validateNonNegativeInteger(d *Dialect, path string, v json.Value) error {
 	n, ok := v.(*json.Integer)
 	if !ok {
-		return fmt.Errorf("%q must be a number", path)
+		return fmt.Errorf("%q must be an integer", path)
 	}
-	if n.Value <= 0 {
-		return fmt.Errorf("%q must be > 0", path)
+	if n.Value < 0 {
+		return fmt.Errorf("%q must be >= 0", path)
 	}
 	return nil
 }
 
-func validatePattern(path string, v json.Value) error {
-	s, ok := v.(*json.String)
-	if !ok {
+func validatePattern(d *Dialect, path string, v json.Value) error {
+	// The regexp itself is compiled with the engine picked for the Validator.
+	if _, ok := v.(*json.String); !ok {
 		return fmt.Errorf("%q must be a string", path)
 	}
-	_, err := regexp.Compile(s.Value)
-	if err != nil {
-		return fmt.Errorf("%q must be a valid regexp: %s", path, err)
-	}
 	return nil
 }
 
-func validateBoolOrSchema(path string, v json.Value) error {
+func validateBoolOrSchema(d *Dialect, path string, v json.Value) error {
 	switch v := v.(type) {
 	case *json.Bool:
 		return nil
 	default:
-		return validateDraft04Schema(path, v)
+		return d.validateSchema(path, v)
 	}
 }
 
-func validateItems(path string, v json.Value) error {
+func validateItems(d *Dialect, path string, v json.Value) error {
 	switch v := v.(type) {
 	case *json.Array:
-		return validateSchemaArray(path, v)
+		return validateSchemaArray(d, path, v)
 	default:
-		return validateDraft04Schema(path, v)
+		return d.validateSchema(path, v)
 	}
 }
 
-func validateSchemaArray(path string, v json.Value) error {
+func validateSchemaArray(d *Dialect, path string, v json.Value) error {
 	a, ok := v.(*json.Array)
 	if !ok {
 		return fmt.Errorf("%q must be an array", path)
@@ -227,7 +234,7 @@ func validateSchemaArray(path string, v json.Value) error {
 		return fmt.Errorf("%q must have at least 1 element", path)
 	}
 	for i, v := range a.Value {
-		err := validateDraft04Schema(path+fmt.Sprintf("/[%d]", i), v)
+		err := d.validateSchema(fmt.Sprintf("%s/%d", path, i), v)
 		if err != nil {
 			return err
 		}
@@ -235,12 +242,12 @@ func validateSchemaArray(path string, v json.Value) error {
 	return nil
 }
 
-func validateStringArray(path string, v json.Value) error {
+func validateStringArray(d *Dialect, path string, v json.Value) error {
 	a, ok := v.(*json.Array)
 	if !ok {
 		return fmt.Errorf("%q must be an array", path)
 	}
-	if len(a.Value) < 1 {
+	if len(a.Value) < 1 && !d.emptyArrays {
 		return fmt.Errorf("%q must have at least 1 element", path)
 	}
 	for _, t := range a.Value {
@@ -255,13 +262,13 @@ func validateStringArray(path string, v json.Value) error {
 	return nil
 }
 
-func validateSchemaCollection(path string, v json.Value) error {
+func validateSchemaCollection(d *Dialect, path string, v json.Value) error {
 	m, ok := v.(*json.Object)
 	if !ok {
 		return fmt.Errorf("%q must be an object", path)
 	}
 	for k, v := range m.Value {
-		err := validateDraft04Schema(path+"/"+k.Value, v)
+		err := d.validateSchema(path+"/"+escapeRefToken(k.Value), v)
 		if err != nil {
 			return err
 		}
@@ -269,34 +276,89 @@ func validateSchemaCollection(path string, v json.Value) error {
 	return nil
 }
 
-func validateDependencies(path string, v json.Value) error {
+func validateDependencies(d *Dialect, path string, v json.Value) error {
 	m, ok := v.(*json.Object)
 	if !ok {
 		return fmt.Errorf("%q must be an object", path)
 	}
 	for k, v := range m.Value {
-		switch v := v.(type) {
-		case *json.Object:
-			err := validateDraft04Schema(path+"/"+k.Value, v)
-			if err != nil {
-				return err
-			}
+		var err error
+		switch v.(type) {
 		case *json.Array:
-			return validateStringArray(path+"/"+k.Value, v)
+			err = validateStringArray(d, path+"/"+escapeRefToken(k.Value), v)
+		case *json.Object, *json.Bool:
+			err = d.validateSchema(path+"/"+escapeRefToken(k.Value), v)
 		default:
-			return fmt.Errorf("%q must be an array or an object", path+"/"+k.Value)
+			err = fmt.Errorf("%q must be an array or an object", path+"/"+escapeRefToken(k.Value))
+		}
+		if err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-func validateEnum(path string, v json.Value) error {
+func validateEnum(d *Dialect, path string, v json.Value) error {
 	a, ok := v.(*json.Array)
 	if !ok {
 		return fmt.Errorf("%q must be an array", path)
 	}
-	if len(a.Value) < 1 {
+	if len(a.Value) < 1 && !d.emptyArrays {
 		return fmt.Errorf("%q must have at least 1 element", path)
 	}
 	return nil
 }
+
+func validateAnyArray(d *Dialect, path string, v json.Value) error {
+	if _, ok := v.(*json.Array); !ok {
+		return fmt.Errorf("%q must be an array", path)
+	}
+	return nil
+}
+
+func validateAnything(d *Dialect, path string, v json.Value) error {
+	return nil
+}
+
+// anchorChecker returns a checker for anchor names matching re.
+func anchorChecker(re *regexp.Regexp) keywordChecker {
+	return func(d *Dialect, path string, v json.Value) error {
+		s, ok := v.(*json.String)
+		if !ok {
+			return fmt.Errorf("%q must be a string", path)
+		}
+		if !re.MatchString(s.Value) {
+			return fmt.Errorf("%q: %q is not a valid anchor name", path, s.Value)
+		}
+		return nil
+	}
+}
+
+func validateVocabulary(d *Dialect, path string, v json.Value) error {
+	m, ok := v.(*json.Object)
+	if !ok {
+		return fmt.Errorf("%q must be an object", path)
+	}
+	for k, v := range m.Value {
+		if err := isValidURI(k.Value); err != nil {
+			return fmt.Errorf("%q: %q must be a valid URI: %s", path, k.Value, err)
+		}
+		if err := validateBoolean(d, path+"/"+escapeRefToken(k.Value), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateDependentRequired(d *Dialect, path string, v json.Value) error {
+	m, ok := v.(*json.Object)
+	if !ok {
+		return fmt.Errorf("%q must be an object", path)
+	}
+	for k, v := range m.Value {
+		if err := validateStringArray(d, path+"/"+escapeRefToken(k.Value), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+package schema
+
+import (
+	"context"
+	stdjson "encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	json "github.com/cesanta/ucl"
+)
+
+// ValidateReader is like ValidateContext, but reads the value from r as it
+// goes instead of taking it parsed, which is meant for values too large to
+// keep in memory. The limits set with WithLimits apply the same way. Arrays
+// and objects are not kept in memory if the schemas that apply to them only
+// have keywords that check their items and properties one by one, like
+// "items", "properties" or "required", so memory use depends on the depth of
+// the value rather than its size. Other keywords, like "enum", "anyOf" or
+// "uniqueItems", need the whole value at once, so it is parsed before it is
+// checked, same for the whole document if the schema uses "$recursiveRef" or
+// "$dynamicRef".
+//
+// Arrays and objects can't be nested more than 10000 levels deep, or
+// Limits.MaxDepth if it is lower, otherwise *IncompleteError with ErrMaxDepth
+// is returned.
+//
+// Violations are reported in the order they are found in the value, so
+// unlike with ValidateContext properties are not sorted, and "required" is
+// checked after all the properties. Numbers are kept exact, like Parse does.
+// Syntax errors stop validation and are returned as is.
+func (v *Validator) ValidateReader(ctx context.Context, r io.Reader) error {
+	if err := ctx.Err(); err != nil {
+		return &IncompleteError{Err: err}
+	}
+	d := stdjson.NewDecoder(r)
+	d.UseNumber()
+	st := &streamer{dec: d, s: v.limitedState(ctx)}
+	var err error
+	if dynamicScope(v.root) {
+		var val json.Value
+		if val, err = parseNext(d, 0); err == nil {
+			err = st.s.validate(v.root, "", val)
+		}
+	} else {
+		err = st.validate([]*node{v.root}, "")
+	}
+	if err = nestingError(err); err == ErrMaxDepth {
+		st.s.limiter.halt(err)
+	}
+	if err == nil {
+		if _, err = d.Token(); err == nil {
+			err = errors.New("unexpected data after the value")
+		} else if err == io.EOF {
+			err = nil
+		}
+	}
+	return st.s.limitedResult(err)
+}
+
+// nestingError returns ErrMaxDepth if err is encoding/json refusing to read
+// arrays and objects nested deeper than it allows, which newer versions of it
+// do before maxNesting is reached, err otherwise.
+func nestingError(err error) error {
+	var se *stdjson.SyntaxError
+	if errors.As(err, &se) && strings.Contains(se.Error(), "max depth") {
+		return ErrMaxDepth
+	}
+	return err
+}
+
+// dynamicScope reports whether any of the schemas reachable from root uses
+// "$recursiveRef" or "$dynamicRef", which depend on the schemas validation
+// went through to get to the value.
+func dynamicScope(root *node) bool {
+	seen := map[*node]bool{}
+	queue := []*node{root}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+		if n.recursiveRef != nil || n.dynamicRef != nil {
+			return true
+		}
+		queue = append(queue, n.inPlace()...)
+		queue = append(queue, n.nested()...)
+	}
+	return false
+}
+
+// streamable returns nodes along with the schemas they apply in place with
+// "$ref" and "allOf", if none of them needs the whole array or object at
+// once.
+func streamable(nodes []*node) ([]*node, bool) {
+	var r []*node
+	queue := append([]*node(nil), nodes...)
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if n.enum != nil || n.constant != nil || len(n.anyOf) > 0 || len(n.oneOf) > 0 || n.not != nil || n.ifSchema != nil ||
+			n.uniqueItems || n.contains != nil || len(n.dependencies) > 0 ||
+			n.unevaluatedItems != nil || n.unevaluatedProperties != nil || len(n.custom) > 0 {
+			return nil, false
+		}
+		r = append(r, n)
+		// Chains of "$ref"s and "allOf"s always end, see findCycle.
+		if n.ref != nil {
+			queue = append(queue, n.ref)
+		}
+		queue = append(queue, n.allOf...)
+	}
+	return r, true
+}
+
+// streamer validates a value while reading it.
+type streamer struct {
+	dec *stdjson.Decoder
+	s   *state
+}
+
+// validate reads the next value, at path and at depth st.s.depth, and
+// validates it against all of nodes.
+func (st *streamer) validate(nodes []*node, path string) error {
+	if len(nodes) == 0 {
+		return st.skip()
+	}
+	tok, err := st.dec.Token()
+	if err != nil {
+		return err
+	}
+	all, ok := streamable(nodes)
+	delim, container := tok.(stdjson.Delim)
+	if !ok || !container {
+		val, err := parseValue(st.dec, tok, st.s.depth)
+		if err != nil {
+			return err
+		}
+		// descend counts val as an item or a property of the current
+		// value, so it needs to start one level up.
+		st.s.depth--
+		defer func() { st.s.depth++ }()
+		for _, n := range nodes {
+			if err := st.s.descend(n, path, val); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if st.s.depth >= maxNesting {
+		return ErrMaxDepth
+	}
+	var empty json.Value = &json.Array{}
+	if delim == '{' {
+		empty = &json.Object{Value: map[json.Key]json.Value{}}
+	}
+	var apply []*node
+	for _, n := range all {
+		if st.s.limiter != nil {
+			if err := st.s.limiter.tick(n.cost(empty), st.s.depth); err != nil {
+				return err
+			}
+		}
+		if n.never {
+			if err := st.s.errs.add(neverError(n, path)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := st.s.validateType(n, path, empty); err != nil {
+			return err
+		}
+		apply = append(apply, n)
+	}
+	st.s.depth++
+	defer func() { st.s.depth-- }()
+	if delim == '{' {
+		return st.object(apply, path)
+	}
+	return st.array(apply, path)
+}
+
+// array validates the items of an array against nodes, after '['. st.s.depth
+// is the depth of the items.
+func (st *streamer) array(nodes []*node, path string) error {
+	count := 0
+	for st.dec.More() {
+		var items []*node
+		for _, n := range nodes {
+			if n.items != nil {
+				items = append(items, n.items)
+			}
+			if count < len(n.itemsList) {
+				items = append(items, n.itemsList[count])
+			} else if n.additionalItems != nil && !n.additionalItems.never {
+				items = append(items, n.additionalItems)
+			}
+		}
+		if err := st.validate(items, path+"/"+strconv.Itoa(count)); err != nil {
+			return err
+		}
+		count++
+	}
+	if _, err := st.dec.Token(); err != nil {
+		return err
+	}
+	for _, n := range nodes {
+		if err := st.s.validateItemCount(n, path, count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// object validates the properties of an object against nodes, after '{'.
+// st.s.depth is the depth of the properties.
+// Only the names of the required properties are kept until the end.
+func (st *streamer) object(nodes []*node, path string) error {
+	required := map[string]bool{}
+	for _, n := range nodes {
+		for _, prop := range n.required {
+			required[prop] = false
+		}
+	}
+	count := 0
+	for st.dec.More() {
+		tok, err := st.dec.Token()
+		if err != nil {
+			return err
+		}
+		prop := tok.(string)
+		count++
+		if _, found := required[prop]; found {
+			required[prop] = true
+		}
+		var subs []*node
+		collect := func(sub *node, ppath string) error {
+			subs = append(subs, sub)
+			return nil
+		}
+		for _, n := range nodes {
+			if _, err := st.s.validateProperty(n, path, prop, collect); err != nil {
+				return err
+			}
+		}
+		if err := st.validate(subs, path+"/"+escapeRefToken(prop)); err != nil {
+			return err
+		}
+	}
+	if _, err := st.dec.Token(); err != nil {
+		return err
+	}
+	has := func(prop string) bool {
+		return required[prop]
+	}
+	for _, n := range nodes {
+		if err := st.s.validatePropertyCount(n, path, count, has); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skip reads the next value without keeping it.
+func (st *streamer) skip() error {
+	depth := 0
+	for {
+		tok, err := st.dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case stdjson.Delim('['), stdjson.Delim('{'):
+			depth++
+		case stdjson.Delim(']'), stdjson.Delim('}'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
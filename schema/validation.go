@@ -2,76 +2,381 @@ package schema
 
 import (
 	"fmt"
-	"net/url"
-	"regexp"
+	"math"
+	"strconv"
 	"strings"
 	"unicode/utf8"
 
 	json "github.com/cesanta/ucl"
 )
 
-// Validator is an entity that validates random pieces of JSON.
+// Validator is an entity that validates random pieces of JSON. The schema is
+// compiled once by NewValidator, so a Validator is meant to be reused. It is
+// safe for concurrent use by multiple goroutines, as long as the custom
+// keywords, formats and regexp engine passed to NewValidator are.
 type Validator struct {
-	schema json.Value
-	loader *Loader
+	root *node
+	// unevaluated is set if evaluated items and properties need to be
+	// tracked during validation.
+	unevaluated bool
+	limits      Limits
+	workers     int
+}
+
+// Option changes the behaviour of NewValidator.
+type Option func(*options)
+
+type options struct {
+	dialect  *Dialect
+	override *Dialect
+	keywords map[string]Keyword
+	// formats are the checkers added with WithFormat.
+	formats       map[string]func(string) error
+	unknownFormat func(path string, format string) error
+	assertFormats bool
+	regexps       RegexpEngine
+	limits        Limits
+	workers       int
+}
+
+// DefaultDialect sets the dialect used for schemas that don't have "$schema".
+// Without this option it is Draft04.
+func DefaultDialect(d *Dialect) Option {
+	return func(o *options) {
+		o.dialect = d
+	}
+}
+
+// OverrideDialect makes NewValidator use d for the schema regardless of its
+// "$schema". Embedded schema resources that set "$schema" themselves, as well
+// as the schemas fetched from the loader, still use the dialect they name.
+func OverrideDialect(d *Dialect) Option {
+	return func(o *options) {
+		o.override = d
+	}
 }
 
 // NewValidator constructs a new Validator. If your schema contains refs to other
-// schemas you need to pass non-nil loader for validation to pass.
-func NewValidator(schema json.Value, loader *Loader) (*Validator, error) {
-	err := ValidateDraft04Schema(schema)
-	if err != nil {
-		return nil, err
+// schemas you need to pass non-nil loader for validation to pass. All the
+// references are resolved here, so the loader is not used after NewValidator
+// returns.
+//
+// The dialect is picked based on "$schema" in the schema, see DefaultDialect
+// for what happens if it is not set. Subschemas with an id can set "$schema"
+// too, to use a different dialect than the rest of the schema. "$schema" of the
+// root schema can also refer to a custom meta-schema available from the loader.
+// If it is based on 2020-12 and lists vocabularies in "$vocabulary", only
+// keywords from those vocabularies are used. NewValidator fails if the
+// meta-schema can't be fetched or is not based on one of the known dialects,
+// unless OverrideDialect is used.
+func NewValidator(schema json.Value, loader *Loader, opts ...Option) (*Validator, error) {
+	o := options{dialect: Draft04}
+	for _, opt := range opts {
+		opt(&o)
 	}
 	if loader == nil {
 		loader = NewLoader()
 	}
-	expandIdsAndRefsAndAddThemToLoader(nil, schema, loader)
-	return &Validator{schema: schema, loader: loader}, nil
+	if err := checkKeywords(o.keywords); err != nil {
+		return nil, err
+	}
+	d := o.override
+	if d == nil {
+		d = DetectDialect(schema)
+	}
+	if d == nil {
+		if obj, ok := schema.(*json.Object); ok {
+			if uri, ok := obj.Find("$schema").(*json.String); ok {
+				var err error
+				if d, err = metaSchemaDialect(loader, uri.Value); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if d == nil {
+		d = o.dialect
+	}
+	err := d.validateSchema("#", schema)
+	if err != nil {
+		return nil, err
+	}
+	c := newCompiler(loader, o.keywords)
+	c.formats, c.unknownFormat, c.assertFormats = o.formats, o.unknownFormat, o.assertFormats
+	if o.regexps != nil {
+		c.regexps = o.regexps
+	}
+	root, err := c.compileRoot(schema, d)
+	if err != nil {
+		return nil, err
+	}
+	return &Validator{root: root, unevaluated: c.unevaluated, limits: o.limits, workers: o.workers}, nil
 }
 
-// Validate checks that val conforms to schema passed to NewValidator.
+func (v *Validator) newState(maxErrors int) *state {
+	s := &state{errs: errorList{max: maxErrors}}
+	if v.unevaluated {
+		s.evaluated = &evaluated{}
+	}
+	if v.workers > 1 {
+		s.workers = make(chan struct{}, v.workers-1)
+	}
+	return s
+}
+
+// Validate checks that val conforms to schema passed to NewValidator. It stops
+// at the first violation found.
 func (v *Validator) Validate(val json.Value) error {
-	return v.validateAgainstSchema("#", val, "#", v.schema)
+	return v.newState(0).check(v.root, "", val)
 }
 
-// getSchemaByRef resolves a schema reference to the actual schema. It returns
-// 2 values: first is the schema referenced by uri, second is the schema referenced
-// by the uri without a fragment part, which is needed to properly resolve references
-// in the first schema.
-func (v *Validator) getSchemaByRef(uri string) (json.Value, json.Value, error) {
-	u, err := url.Parse(uri)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to parse %q: %s", uri, err)
+// ValidateAll is like Validate, but instead of stopping at the first violation
+// it keeps going and returns all of them as Errors. If maxErrors is greater
+// than 0, validation stops after that many errors were found.
+func (v *Validator) ValidateAll(val json.Value, maxErrors int) error {
+	s := v.newState(maxErrors)
+	if err := s.validate(v.root, "", val); err != nil && err != errTooManyErrors {
+		return err
 	}
-	s, ref := v.schema, u.Fragment
+	if len(s.errs.errs) > 0 {
+		return Errors(s.errs.errs)
+	}
+	return nil
+}
 
-	if !strings.HasPrefix(uri, "#") {
-		u.Fragment = ""
-		uri := u.String()
-		s, err = v.loader.Get(uri)
-		if err != nil {
-			return nil, nil, err
+// state holds everything specific to a single validation run.
+type state struct {
+	errs errorList
+	// evaluated collects items and properties of the current value evaluated
+	// by the schema being applied to it. It is nil if nothing in the schema
+	// needs to know that.
+	evaluated *evaluated
+	// scope is the dynamic scope: schema resources that validation went
+	// through to get to the current schema, outermost first.
+	scope []*node
+
+	// out builds the output unit of the current schema. It is nil unless one
+	// of the output formats is requested, see output.go.
+	out     *unitBuilder
+	verbose bool
+	// via and isolated describe how the next schema is applied, for its
+	// output unit: via is the reference keyword that leads to it, and
+	// isolated is set if it is applied by isolate, in which case isolatedAt
+	// is the number of violations recorded before that.
+	via        string
+	isolated   bool
+	isolatedAt int
+
+	// annotate is set if annotations need to be collected, see
+	// annotations.go.
+	annotate    bool
+	annotations []*Annotation
+	// defaults is set if missing properties need to be filled in, see
+	// defaults.go.
+	defaults bool
+	// coerce is set if items and properties need to be converted to the
+	// types their schemas require, see coercion.go.
+	coerce bool
+
+	// limiter is set by ValidateContext, see limits.go.
+	limiter *limiter
+	// depth is how deep the current value is in the one being validated: 0
+	// for the value itself, 1 for its items and properties and so on.
+	depth int
+	// workers holds a token for every goroutine validating the value, other
+	// than the one that called the Validator. It is nil unless Parallel is
+	// used, see parallel.go.
+	workers chan struct{}
+}
+
+// evaluated is a set of items and properties of a value.
+type evaluated struct {
+	items      int // Items with smaller indices are evaluated.
+	allItems   bool
+	indices    map[int]bool // Other evaluated items, from "contains".
+	properties map[string]bool
+}
+
+func (e *evaluated) merge(other *evaluated) {
+	if other.items > e.items {
+		e.items = other.items
+	}
+	e.allItems = e.allItems || other.allItems
+	for i := range other.indices {
+		e.addIndex(i)
+	}
+	for p := range other.properties {
+		e.addProperty(p)
+	}
+}
+
+func (e *evaluated) addIndex(i int) {
+	if e.indices == nil {
+		e.indices = map[int]bool{}
+	}
+	e.indices[i] = true
+}
+
+func (e *evaluated) addProperty(p string) {
+	if e.properties == nil {
+		e.properties = map[string]bool{}
+	}
+	e.properties[p] = true
+}
+
+// isolate validates val against n and returns the first violation found,
+// without recording it anywhere, along with the items and properties
+// evaluated by n.
+func (s *state) isolate(n *node, path string, val json.Value) (*evaluated, error) {
+	errs, outer, defaults, coerce := s.errs, s.evaluated, s.defaults, s.coerce
+	s.errs = errorList{max: 1, limiter: errs.limiter}
+	// The value must not change depending on whether it is valid against n.
+	s.defaults, s.coerce = false, false
+	if outer != nil {
+		s.evaluated = &evaluated{}
+	}
+	if s.out != nil {
+		// The output shows everything that is wrong with the value.
+		s.errs.max = 0
+		s.isolated, s.isolatedAt = true, len(errs.errs)
+	}
+	annotations := len(s.annotations)
+	err := s.validate(n, path, val)
+	sub, ev := s.errs, s.evaluated
+	s.errs, s.evaluated, s.defaults, s.coerce = errs, outer, defaults, coerce
+	if err != nil && err != errTooManyErrors {
+		return nil, err
+	}
+	if len(sub.errs) > 0 {
+		// Nothing in n applies to the value.
+		s.annotations = s.annotations[:annotations]
+		return nil, sub.errs[0]
+	}
+	return ev, nil
+}
+
+// check validates val against n in isolation and returns the first violation
+// found, without recording it anywhere. It is used by keywords like "not"
+// that only need to know whether the value is valid.
+func (s *state) check(n *node, path string, val json.Value) error {
+	_, err := s.isolate(n, path, val)
+	return err
+}
+
+// checkPart is like check, but val is an item or a property of the current
+// value, like the items checked by "contains".
+func (s *state) checkPart(n *node, path string, val json.Value) error {
+	s.depth++
+	err := s.check(n, path, val)
+	s.depth--
+	return err
+}
+
+// evaluate is like check, but if val is valid against n, the items and
+// properties evaluated by n count as evaluated by the current schema. It is
+// used by keywords like "anyOf", that don't fail just because one of the
+// subschemas does.
+func (s *state) evaluate(n *node, path string, val json.Value) error {
+	ev, err := s.isolate(n, path, val)
+	if err == nil && s.evaluated != nil {
+		s.evaluated.merge(ev)
+	}
+	return err
+}
+
+// descend validates val, which is an item or a property of the current
+// value, against n.
+func (s *state) descend(n *node, path string, val json.Value) error {
+	s.depth++
+	defer func() { s.depth-- }()
+	if s.evaluated == nil {
+		return s.validateNode(n, path, val)
+	}
+	outer := s.evaluated
+	s.evaluated = &evaluated{}
+	err := s.validateNode(n, path, val)
+	s.evaluated = outer
+	return err
+}
+
+// evaluatedItems marks items with indices less than i as evaluated, and all
+// of them if i is negative.
+func (s *state) evaluatedItems(i int) {
+	switch {
+	case s.evaluated == nil:
+	case i < 0:
+		s.evaluated.allItems = true
+	case i > s.evaluated.items:
+		s.evaluated.items = i
+	}
+}
+
+// evaluatedItem marks the item with index i as evaluated.
+func (s *state) evaluatedItem(i int) {
+	if s.evaluated != nil {
+		s.evaluated.addIndex(i)
+	}
+}
+
+func (s *state) evaluatedProperty(p string) {
+	if s.evaluated != nil {
+		s.evaluated.addProperty(p)
+	}
+}
+
+// resolveRecursiveRef returns the schema "$recursiveRef" refers to: target,
+// unless it has "$recursiveAnchor" set to true, in which case it is the
+// outermost schema resource in the dynamic scope that has it set too.
+func (s *state) resolveRecursiveRef(target *node) *node {
+	if !target.recursiveAnchor {
+		return target
+	}
+	for _, r := range s.scope {
+		if r.recursiveAnchor {
+			return r
 		}
 	}
+	return target
+}
 
-	r, err := resolveRef(s, ref)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to resolve ref %q: %s", uri, err)
+// resolveDynamicRef returns the schema "$dynamicRef" of n refers to: the
+// outermost schema in the dynamic scope with the "$dynamicAnchor" named in the
+// reference, or the statically resolved target if it doesn't have such an
+// anchor itself.
+func (s *state) resolveDynamicRef(n *node) *node {
+	if n.dynamicRefAnchor == "" {
+		return n.dynamicRef
+	}
+	for _, r := range s.scope {
+		if target, found := r.dynamicAnchors[n.dynamicRefAnchor]; found {
+			return target
+		}
 	}
-	return r, s, nil
+	return n.dynamicRef
 }
 
-func isOfType(val json.Value, t string) bool {
-	switch val.(type) {
+func isOfType(d *Dialect, val json.Value, t string) bool {
+	switch val := val.(type) {
 	case *json.Array:
 		return t == "array"
 	case *json.Bool:
 		return t == "boolean"
 	case *json.Number:
+		if t == "integer" && d.integerFloats {
+			return val.Value == math.Trunc(val.Value) && !math.IsInf(val.Value, 0)
+		}
 		return t == "number"
 	case *json.Integer:
 		return t == "number" || t == "integer"
+	case *Decimal:
+		if t == "integer" {
+			if d.integerFloats {
+				return toRat(val).IsInt()
+			}
+			// Same as *json.Integer and *json.Number.
+			return !strings.ContainsAny(val.Text, ".eE")
+		}
+		return t == "number"
 	case *json.Null:
 		return t == "null"
 	case *json.Object:
@@ -82,680 +387,586 @@ func isOfType(val json.Value, t string) bool {
 	return false
 }
 
-func (v *Validator) validateAgainstSchema(path string, val json.Value, schemaPath string, schema_ json.Value) error {
-	schema, ok := schema_.(*json.Object)
-	if !ok {
-		return fmt.Errorf("%q: schema must be an object", schemaPath)
+// validate checks val against n, which applies to the same value as the
+// current schema. Violations are recorded in s.errs, the returned error is
+// non-nil only if validation needs to stop. If val is valid against n, the
+// items and properties evaluated by n count as evaluated by the current
+// schema.
+func (s *state) validate(n *node, path string, val json.Value) error {
+	if s.evaluated == nil {
+		return s.validateNode(n, path, val)
+	}
+	outer, before := s.evaluated, len(s.errs.errs)
+	s.evaluated = &evaluated{}
+	err := s.validateNode(n, path, val)
+	if err == nil && len(s.errs.errs) == before {
+		outer.merge(s.evaluated)
+	}
+	s.evaluated = outer
+	return err
+}
+
+// follow validates val against target, which the current schema refers to
+// with keyword, like "$ref".
+func (s *state) follow(keyword string, target *node, path string, val json.Value) error {
+	if s.out != nil {
+		s.via = keyword
 	}
+	return s.validate(target, path, val)
+}
 
-	ref, found := schema.Lookup("$ref")
-	if found {
-		sref, ok := ref.(*json.String)
-		if !ok {
-			return fmt.Errorf("%q: must be a string", schemaPath+"/$ref")
+// validateNode checks val against n, with s.evaluated collecting items and
+// properties evaluated by n.
+func (s *state) validateNode(n *node, path string, val json.Value) error {
+	if s.limiter != nil {
+		if err := s.limiter.tick(n.cost(val), s.depth); err != nil {
+			return err
 		}
-		s, whole, err := v.getSchemaByRef(sref.Value)
-		if err != nil {
+	}
+	if s.out != nil {
+		defer s.enter(n, path)()
+	}
+	if s.annotate {
+		defer s.collect(n, path)()
+	}
+	if n.resource {
+		s.scope = append(s.scope, n)
+		defer func() { s.scope = s.scope[:len(s.scope)-1] }()
+	}
+	if n.ref != nil {
+		if err := s.follow("$ref", n.ref, path, val); err != nil {
 			return err
 		}
-		nv, err := NewValidator(whole, v.loader)
-		if err != nil {
+	}
+	if n.recursiveRef != nil {
+		if err := s.follow("$recursiveRef", s.resolveRecursiveRef(n.recursiveRef), path, val); err != nil {
 			return err
 		}
-		return nv.validateAgainstSchema(path, val, sref.Value, s)
 	}
-
-	t, found := schema.Lookup("type")
-	if found {
-		switch t := t.(type) {
-		case *json.String:
-			if !isOfType(val, t.Value) {
-				return fmt.Errorf("%q: must be of type %q", path, t.Value)
-			}
-		case *json.Array:
-			match := false
-			for i, v := range t.Value {
-				t, ok := v.(*json.String)
-				if !ok {
-					return fmt.Errorf("%q: must be a string", schemaPath+fmt.Sprintf("/[%d]", i))
-				}
-				if isOfType(val, t.Value) {
-					match = true
-					break
-				}
-			}
-			if !match {
-				return fmt.Errorf("%q: must be of one of the types %s", path, t)
-			}
-		default:
-			return fmt.Errorf("%q: must be a string or an array", schemaPath+"/type")
+	if n.dynamicRef != nil {
+		if err := s.follow("$dynamicRef", s.resolveDynamicRef(n), path, val); err != nil {
+			return err
 		}
 	}
+	if n.never {
+		return s.errs.add(neverError(n, path))
+	}
+	if err := s.validateType(n, path, val); err != nil {
+		return err
+	}
 
-	i, found := schema.Lookup("allOf")
-	if found {
-		a, ok := i.(*json.Array)
-		if !ok {
-			return fmt.Errorf("%q must be an array", schemaPath+"/allOf")
-		}
-		if len(a.Value) < 1 {
-			return fmt.Errorf("%q must have at least 1 element", schemaPath+"/allOf")
-		}
-		for i, s := range a.Value {
-			err := ValidateDraft04Schema(s)
-			if err != nil {
-				return fmt.Errorf("%q must be a valid schema: %s", fmt.Sprintf("%s/allOf/[%d]", schemaPath, i), err)
-			}
-			err = v.validateAgainstSchema(path, val, fmt.Sprintf("%s/allOf/[%d]", schemaPath, i), s)
-			if err != nil {
-				return err
-			}
+	for _, sub := range n.allOf {
+		if err := s.validate(sub, path, val); err != nil {
+			return err
 		}
 	}
 
-	i, found = schema.Lookup("anyOf")
-	if found {
-		a, ok := i.(*json.Array)
-		if !ok {
-			return fmt.Errorf("%q must be an array", schemaPath+"/anyOf")
-		}
-		if len(a.Value) < 1 {
-			return fmt.Errorf("%q must have at least 1 element", schemaPath+"/anyOf")
-		}
-		errs := []string{}
-		for i, s := range a.Value {
-			err := ValidateDraft04Schema(s)
-			if err != nil {
-				return fmt.Errorf("%q must be a valid schema: %s", fmt.Sprintf("%s/anyOf/[%d]", schemaPath, i), err)
+	if len(n.anyOf) > 0 {
+		msgs := []string{}
+		for _, sub := range n.anyOf {
+			if err := s.evaluate(sub, path, val); err != nil {
+				msgs = append(msgs, err.Error())
 			}
-			err = v.validateAgainstSchema(path, val, fmt.Sprintf("%s/anyOf/[%d]", schemaPath, i), s)
+		}
+		if len(msgs) == len(n.anyOf) {
+			err := s.errs.add(newValidationError(path, n.path, "anyOf", nil, "must be valid against at least one of the schemas in %q, but it is not:\n%s",
+				n.path+"/anyOf", strings.Join(msgs, "\n")))
 			if err != nil {
-				errs = append(errs, err.Error())
+				return err
 			}
 		}
-		if len(errs) == len(a.Value) {
-			return fmt.Errorf("%q must be valid against at least one of the schemas in %q, but it is not:\n%s", path, schemaPath+"/anyOf", strings.Join(errs, "\n"))
-		}
 	}
 
-	i, found = schema.Lookup("oneOf")
-	if found {
-		a, ok := i.(*json.Array)
-		if !ok {
-			return fmt.Errorf("%q must be an array", schemaPath+"/oneOf")
-		}
-		if len(a.Value) < 1 {
-			return fmt.Errorf("%q must have at least 1 element", schemaPath+"/oneOf")
-		}
-		errs := make([]string, len(a.Value))
+	if len(n.oneOf) > 0 {
+		msgs := make([]string, len(n.oneOf))
 		valid := []int{}
-		for i, s := range a.Value {
-			err := ValidateDraft04Schema(s)
-			if err != nil {
-				return fmt.Errorf("%q must be a valid schema: %s", fmt.Sprintf("%s/oneOf/[%d]", schemaPath, i), err)
-			}
-			err = v.validateAgainstSchema(path, val, fmt.Sprintf("%s/oneOf/[%d]", schemaPath, i), s)
-			if err != nil {
-				errs[i] = err.Error()
+		for i, sub := range n.oneOf {
+			if err := s.evaluate(sub, path, val); err != nil {
+				msgs[i] = err.Error()
 			} else {
 				valid = append(valid, i)
 			}
 		}
 		if len(valid) == 0 {
-			return fmt.Errorf("%q must be valid against against one of the schemas in %q, but it is not:\n%s", path, schemaPath+"/oneOf", strings.Join(errs, "\n"))
+			err := s.errs.add(newValidationError(path, n.path, "oneOf", nil, "must be valid against one of the schemas in %q, but it is not:\n%s",
+				n.path+"/oneOf", strings.Join(msgs, "\n")))
+			if err != nil {
+				return err
+			}
 		}
 		if len(valid) > 1 {
 			ss := []string{}
 			for _, vv := range valid {
-				ss = append(ss, fmt.Sprintf("%s/oneOf/[%d]", schemaPath, vv))
+				ss = append(ss, fmt.Sprintf("%s/oneOf/%d", n.path, vv))
+			}
+			err := s.errs.add(newValidationError(path, n.path, "oneOf", params{"valid": valid}, "must be valid against exactly one of the schemas in %q, but it is valid against %s",
+				n.path+"/oneOf", strings.Join(ss, " and ")))
+			if err != nil {
+				return err
 			}
-			return fmt.Errorf("%q must be valid against exactly one of the schemas in %q, but it is valid against %s", path, schemaPath+"/oneOf", strings.Join(ss, " and "))
 		}
 	}
 
-	if not, found := schema.Lookup("not"); found {
-		err := ValidateDraft04Schema(not)
-		if err != nil {
-			return fmt.Errorf("%q must be a valid schema: %s", schemaPath+"/not", err)
-		}
-		err = v.validateAgainstSchema(path, val, schemaPath+"/not", not)
-		if err == nil {
-			return fmt.Errorf("%q must not be valid against %q, but it is", path, schemaPath+"/not")
+	if n.not != nil {
+		if err := s.check(n.not, path, val); err == nil {
+			if err := s.errs.add(newValidationError(path, n.path, "not", nil, "must not be valid against %q, but it is", n.path+"/not")); err != nil {
+				return err
+			}
 		}
 	}
 
-	i, found = schema.Lookup("enum")
-	if found {
-		enum, ok := i.(*json.Array)
-		if !ok {
-			return fmt.Errorf("%q must be an array", schemaPath+"/enum")
-		}
-		if len(enum.Value) < 1 {
-			return fmt.Errorf("%q must have at least one element", schemaPath+"/enum")
+	// "then" and "else" are ignored without "if", and there is nothing to do
+	// if the branch that applies is not set.
+	if n.ifSchema != nil {
+		branch := n.elseSchema
+		if s.evaluate(n.ifSchema, path, val) == nil {
+			branch = n.thenSchema
+		}
+		if branch != nil {
+			if err := s.validate(branch, path, val); err != nil {
+				return err
+			}
 		}
+	}
+
+	if n.enum != nil {
 		valid := false
-		for _, item := range enum.Value {
+		for _, item := range n.enum.Value {
 			if equal(item, val) {
 				valid = true
 				break
 			}
 		}
 		if !valid {
-			return fmt.Errorf("%q must be one of %s", path, enum)
+			if err := s.errs.add(newValidationError(path, n.path, "enum", params{"enum": n.enum}, "must be one of %s", n.enum)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if n.constant != nil && !equal(n.constant, val) {
+		if err := s.errs.add(newValidationError(path, n.path, "const", params{"const": n.constant}, "must be equal to %s", n.constant)); err != nil {
+			return err
 		}
 	}
 
 	var err error
 	switch val := val.(type) {
 	case *json.String:
-		err = v.validateString(path, val, schemaPath, schema)
+		err = s.validateString(n, path, val)
 	case *json.Array:
-		err = v.validateArray(path, val, schemaPath, schema)
+		err = s.validateArray(n, path, val)
 	case *json.Object:
-		err = v.validateObject(path, val, schemaPath, schema)
-	case *json.Number:
-		err = v.validateNumber(path, val, schemaPath, schema)
-	case *json.Integer:
-		err = v.validateInteger(path, val, schemaPath, schema)
+		err = s.validateObject(n, path, val)
+	case *json.Number, *json.Integer, *Decimal:
+		err = s.validateNumber(n, path, val)
 	}
-	return err
+	if err != nil {
+		return err
+	}
+	if err := s.validateCustom(n, path, val); err != nil {
+		return err
+	}
+	// Unevaluated items and properties are only known once everything else
+	// is done.
+	return s.validateUnevaluated(n, path, val)
 }
 
-func (v *Validator) validateString(path string, val *json.String, schemaPath string, schema *json.Object) error {
-	x, found := schema.Lookup("minLength")
-	if found {
-		minLen, ok := x.(*json.Integer)
-		if !ok {
-			return fmt.Errorf("%q must be an integer", schemaPath+"/minLength")
+// neverError is the violation of the false schema n by the value at path.
+func neverError(n *node, path string) *ValidationError {
+	return &ValidationError{
+		InstancePath: path,
+		SchemaPath:   n.path,
+		Keyword:      "false",
+		Message:      fmt.Sprintf("is not allowed by %q", n.path),
+	}
+}
+
+// validateType checks "type" of n. For arrays and objects only the type of
+// val matters, not what is in it.
+func (s *state) validateType(n *node, path string, val json.Value) error {
+	if len(n.types) == 0 {
+		return nil
+	}
+	for _, t := range n.types {
+		if isOfType(n.dialect, val, t) {
+			return nil
 		}
-		if utf8.RuneCountInString(val.Value) < int(minLen.Value) {
-			return fmt.Errorf("%q must have at least %d characters", path, int(minLen.Value))
+	}
+	if len(n.types) == 1 {
+		return s.errs.add(newValidationError(path, n.path, "type", params{"type": n.types[0]}, "must be of type %q", n.types[0]))
+	}
+	return s.errs.add(newValidationError(path, n.path, "type", params{"type": n.types}, "must be of one of the types %q", n.types))
+}
+
+func (s *state) validateString(n *node, path string, val *json.String) error {
+	if n.minLength >= 0 && utf8.RuneCountInString(val.Value) < n.minLength {
+		if err := s.errs.add(newValidationError(path, n.path, "minLength", params{"limit": n.minLength}, "must have at least %d characters", n.minLength)); err != nil {
+			return err
 		}
 	}
-	x, found = schema.Lookup("maxLength")
-	if found {
-		maxLen, ok := x.(*json.Integer)
-		if !ok {
-			return fmt.Errorf("%q must be an integer", schemaPath+"/maxLength")
+	if n.maxLength >= 0 && utf8.RuneCountInString(val.Value) > n.maxLength {
+		if err := s.errs.add(newValidationError(path, n.path, "maxLength", params{"limit": n.maxLength}, "must have at most %d characters", n.maxLength)); err != nil {
+			return err
 		}
-		if utf8.RuneCountInString(val.Value) > int(maxLen.Value) {
-			return fmt.Errorf("%q must have at most %d characters", path, int(maxLen.Value))
+	}
+	if n.pattern != nil {
+		pattern := n.pattern.String()
+		if ok, err := n.pattern.MatchString(val.Value); err != nil {
+			if err := s.errs.add(newValidationError(path, n.path, "pattern", params{"pattern": pattern}, "can't be matched against regexp %q: %s", pattern, err)); err != nil {
+				return err
+			}
+		} else if !ok {
+			if err := s.errs.add(newValidationError(path, n.path, "pattern", params{"pattern": pattern}, "must match regexp %q", pattern)); err != nil {
+				return err
+			}
 		}
 	}
-	x, found = schema.Lookup("pattern")
-	if found {
-		pattern, ok := x.(*json.String)
-		if !ok {
-			return fmt.Errorf("%q must be a string", schemaPath+"/pattern")
+	if n.formatCheck != nil {
+		if err := n.formatCheck(val.Value); err != nil {
+			err := s.errs.add(newValidationError(path, n.path, "format", params{"format": n.format}, "does not comply with format %q: %s", n.format, err))
+			if err != nil {
+				return err
+			}
 		}
-		re, err := regexp.Compile(pattern.Value)
+	}
+	return nil
+}
+
+func (s *state) validateArray(n *node, path string, val *json.Array) error {
+	if s.coerce {
+		coerceItems(n, val)
+	}
+	if n.items != nil {
+		err := s.forEach(len(val.Value), func(w *state, i int) error {
+			return w.descend(n.items, path+"/"+strconv.Itoa(i), val.Value[i])
+		})
 		if err != nil {
-			return fmt.Errorf("%q must be a valid regexp: %s", schemaPath+"/pattern", err)
-		}
-		if !re.MatchString(val.Value) {
-			return fmt.Errorf("%q must match regexp %q", path, pattern.Value)
+			return err
 		}
+		s.evaluatedItems(-1)
 	}
-	x, found = schema.Lookup("format")
-	if found {
-		format, ok := x.(*json.String)
-		if !ok {
-			return fmt.Errorf("%q must be a string", schemaPath+"/format")
+	for i := 0; i < len(n.itemsList) && i < len(val.Value); i++ {
+		if err := s.descend(n.itemsList[i], path+"/"+strconv.Itoa(i), val.Value[i]); err != nil {
+			return err
 		}
-		err := verifyFormat(val.Value, format.Value)
+		s.evaluatedItems(i + 1)
+	}
+	if n.additionalItems != nil && !n.additionalItems.never && len(n.itemsList) < len(val.Value) {
+		first := len(n.itemsList)
+		err := s.forEach(len(val.Value)-first, func(w *state, i int) error {
+			return w.descend(n.additionalItems, path+"/"+strconv.Itoa(first+i), val.Value[first+i])
+		})
 		if err != nil {
-			return fmt.Errorf("%q does not comply with format %q: %s", path, format.Value, err)
+			return err
 		}
+		s.evaluatedItems(-1)
 	}
-	return nil
-}
-
-func (v *Validator) validateArray(path string, val *json.Array, schemaPath string, schema *json.Object) error {
-	x, found := schema.Lookup("items")
-	// If "items" is not present it is assumed to be an empty object, which
-	// means that any item is valid and "additionalItems" is ignored.
-	if found {
-		switch items := x.(type) {
-		case *json.Object:
-			err := ValidateDraft04Schema(items)
+	if err := s.validateItemCount(n, path, len(val.Value)); err != nil {
+		return err
+	}
+	if n.uniqueItems {
+		if i, j, found := findDuplicate(val); found {
+			err := s.errs.add(newValidationError(path, n.path, "uniqueItems", params{"duplicates": []int{i, j}},
+				"all items must be unique, but item %d is equal to item %d", i, j))
 			if err != nil {
-				return fmt.Errorf("%q must be a valid schema: %s", schemaPath+"/items")
-			}
-			for i, item := range val.Value {
-				err := v.validateAgainstSchema(fmt.Sprintf("%s/[%d]", path, i), item, schemaPath+"/items", items)
-				if err != nil {
-					return err
-				}
-			}
-		case *json.Array:
-			for i, item := range items.Value {
-				err := ValidateDraft04Schema(item)
-				if err != nil {
-					return fmt.Errorf("%q must be a valid schema: %s", fmt.Sprintf("%s/[%d]", schemaPath, i), err)
-				}
+				return err
 			}
-			for i := 0; i < len(items.Value) && i < len(val.Value); i++ {
-				err := v.validateAgainstSchema(fmt.Sprintf("%s/[%d]", path, i), val.Value[i],
-					fmt.Sprintf("%s/[%d]", schemaPath, i), items.Value[i])
-				if err != nil {
-					return err
-				}
+		}
+	}
+	if n.contains != nil {
+		min := 1
+		if n.minContains >= 0 {
+			min = n.minContains
+		}
+		// Items valid against "contains" may count as evaluated, and then
+		// all of them have to be checked.
+		annotate := n.dialect.containsEvaluates && s.evaluated != nil
+		count := 0
+		for i, item := range val.Value {
+			if count >= min && n.maxContains < 0 && !annotate {
+				break
 			}
-			ai, found := schema.Lookup("additionalItems")
-			if found {
-				switch ai := ai.(type) {
-				case *json.Bool:
-					if ai.Value == false && len(items.Value) < len(val.Value) {
-						return fmt.Errorf("%q must have not more than %d items", path, len(items.Value))
-					}
-				case *json.Object:
-					err := ValidateDraft04Schema(ai)
-					if err != nil {
-						return fmt.Errorf("%q must be a valid schema: %s", schemaPath+"/additionalItems", err)
-					}
-					for i := len(items.Value); i < len(val.Value); i++ {
-						err := v.validateAgainstSchema(fmt.Sprintf("%s/[%d]", path, i), val.Value[i],
-							schemaPath+"/additionalItems", ai)
-						if err != nil {
-							return err
-						}
-					}
-				default:
-					return fmt.Errorf("%q must be an array or a boolean", schemaPath+"/additionalItems")
+			if s.checkPart(n.contains, path+"/"+strconv.Itoa(i), item) == nil {
+				count++
+				if annotate {
+					s.evaluatedItem(i)
 				}
 			}
-		default:
-			return fmt.Errorf("%q must be an array or an object", schemaPath+"/items")
 		}
-	}
-	x, found = schema.Lookup("maxItems")
-	if found {
-		maxItems, ok := x.(*json.Integer)
-		if !ok {
-			return fmt.Errorf("%q must be an integer", schemaPath+"/maxItems")
+		var err error
+		switch {
+		case count < min && n.minContains >= 0:
+			err = s.errs.add(newValidationError(path, n.path, "minContains", params{"limit": min}, "must contain at least %d items valid against %q", min, n.path+"/contains"))
+		case count < min:
+			err = s.errs.add(newValidationError(path, n.path, "contains", nil, "must contain an item valid against %q", n.path+"/contains"))
+		case n.maxContains >= 0 && count > n.maxContains:
+			err = s.errs.add(newValidationError(path, n.path, "maxContains", params{"limit": n.maxContains}, "must contain at most %d items valid against %q", n.maxContains, n.path+"/contains"))
 		}
-		if len(val.Value) > int(maxItems.Value) {
-			return fmt.Errorf("%q must have at most %d items", path, int(maxItems.Value))
+		if err != nil {
+			return err
 		}
 	}
-	x, found = schema.Lookup("minItems")
-	if found {
-		minItems, ok := x.(*json.Integer)
-		if !ok {
-			return fmt.Errorf("%q must be an integer", schemaPath+"/minItems")
-		}
-		if len(val.Value) < int(minItems.Value) {
-			return fmt.Errorf("%q must have at least %d items", path, int(minItems.Value))
+	return nil
+}
+
+// validateItemCount checks the keywords of n that limit the number of items
+// of an array with count items.
+func (s *state) validateItemCount(n *node, path string, count int) error {
+	if n.additionalItems != nil && n.additionalItems.never && len(n.itemsList) < count {
+		keyword := "additionalItems"
+		if n.dialect.prefixItems {
+			keyword = "items"
+		}
+		err := s.errs.add(newValidationError(path, n.path, keyword, params{"limit": len(n.itemsList)}, "must have not more than %d items", len(n.itemsList)))
+		if err != nil {
+			return err
 		}
 	}
-	x, found = schema.Lookup("uniqueItems")
-	if found {
-		u, ok := x.(*json.Bool)
-		if !ok {
-			return fmt.Errorf("%q must be a boolean", schemaPath+"/uniqueItems")
+	if n.maxItems >= 0 && count > n.maxItems {
+		if err := s.errs.add(newValidationError(path, n.path, "maxItems", params{"limit": n.maxItems}, "must have at most %d items", n.maxItems)); err != nil {
+			return err
 		}
-		if u.Value {
-			if err := uniqueItems(val); err != nil {
-				return fmt.Errorf("%q: %s", path, err)
-			}
+	}
+	if n.minItems >= 0 && count < n.minItems {
+		if err := s.errs.add(newValidationError(path, n.path, "minItems", params{"limit": n.minItems}, "must have at least %d items", n.minItems)); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-func (v *Validator) validateObject(path string, val *json.Object, schemaPath string, schema *json.Object) error {
-	x, found := schema.Lookup("maxProperties")
-	if found {
-		maxProps, ok := x.(*json.Integer)
-		if !ok {
-			return fmt.Errorf("%q must be an integer", schemaPath+"/maxProperties")
-		}
-		if len(val.Value) > int(maxProps.Value) {
-			return fmt.Errorf("%q must have at most %d properties", path, int(maxProps.Value))
-		}
+func (s *state) validateObject(n *node, path string, val *json.Object) error {
+	if s.defaults {
+		fillDefaults(n, val)
+	}
+	if s.coerce {
+		coerceProperties(n, val)
+	}
+	has := func(prop string) bool {
+		_, found := val.Lookup(prop)
+		return found
+	}
+	if err := s.validatePropertyCount(n, path, len(val.Value), has); err != nil {
+		return err
+	}
+	// Properties are checked in sorted order so that ValidateAll reports errors
+	// in a stable order.
+	props := sortedKeys(val)
+	evaluated := make([]bool, len(props))
+	err := s.forEach(len(props), func(w *state, i int) error {
+		v := val.Find(props[i])
+		var err error
+		evaluated[i], err = w.validateProperty(n, path, props[i], func(sub *node, ppath string) error {
+			return w.descend(sub, ppath, v)
+		})
+		return err
+	})
+	if err != nil {
+		return err
 	}
-	x, found = schema.Lookup("minProperties")
-	if found {
-		minProps, ok := x.(*json.Integer)
-		if !ok {
-			return fmt.Errorf("%q must be an integer", schemaPath+"/minProperties")
-		}
-		if len(val.Value) < int(minProps.Value) {
-			return fmt.Errorf("%q must have at least %d properties", path, int(minProps.Value))
+	for i, prop := range props {
+		if evaluated[i] {
+			s.evaluatedProperty(prop)
 		}
 	}
-	x, found = schema.Lookup("required")
-	if found {
-		req, ok := x.(*json.Array)
-		if !ok || len(req.Value) < 1 {
-			return fmt.Errorf("%q must be an array with at least one element", schemaPath+"/required")
+	for _, dep := range n.dependencies {
+		if _, found := val.Lookup(dep.property); !found {
+			continue
 		}
-		for i, p := range req.Value {
-			prop, ok := p.(*json.String)
-			if !ok {
-				return fmt.Errorf("%q must be a string", fmt.Sprintf("%s/required/[%d]", schemaPath, i))
+		for _, req := range dep.required {
+			if _, found := val.Lookup(req); !found {
+				err := s.errs.add(newValidationError(path, n.path, dep.keyword, params{"property": dep.property, "dependency": req},
+					"%q requires %q to be also present", dep.property, req))
+				if err != nil {
+					return err
+				}
 			}
-			_, found := val.Lookup(prop.Value)
-			if !found {
-				return fmt.Errorf("%q must have property %q", path, prop.Value)
+		}
+		if dep.schema != nil {
+			if err := s.validate(dep.schema, path, val); err != nil {
+				return err
 			}
 		}
 	}
-	type schemaWithPath struct {
-		schema json.Value
-		path   string
-	}
-	validateWith := map[string][]schemaWithPath{}
-	for k := range val.Value {
-		validateWith[k.Value] = nil
-	}
-	x, found = schema.Lookup("properties")
-	if found {
-		props, ok := x.(*json.Object)
-		if !ok {
-			return fmt.Errorf("%q must be an object", schemaPath+"/properties")
-		}
-		for k, v := range props.Value {
-			err := ValidateDraft04Schema(v)
-			if err != nil {
-				return fmt.Errorf("%q must be a valid schema: %s", schemaPath+"/properties/"+k.Value, err)
-			}
-			_, found = validateWith[k.Value]
-			if found {
-				validateWith[k.Value] = []schemaWithPath{{v, schemaPath + "/properties/" + k.Value}}
-			}
+	return nil
+}
+
+// validatePropertyCount checks the keywords of n that limit the properties of
+// an object at path with count properties. has reports whether it has a
+// property.
+func (s *state) validatePropertyCount(n *node, path string, count int, has func(prop string) bool) error {
+	if n.maxProperties >= 0 && count > n.maxProperties {
+		if err := s.errs.add(newValidationError(path, n.path, "maxProperties", params{"limit": n.maxProperties}, "must have at most %d properties", n.maxProperties)); err != nil {
+			return err
 		}
 	}
-	x, found = schema.Lookup("patternProperties")
-	if found {
-		pprops, ok := x.(*json.Object)
-		if !ok {
-			return fmt.Errorf("%q must be an object", schemaPath+"/patternProperties")
+	if n.minProperties >= 0 && count < n.minProperties {
+		if err := s.errs.add(newValidationError(path, n.path, "minProperties", params{"limit": n.minProperties}, "must have at least %d properties", n.minProperties)); err != nil {
+			return err
 		}
-		for k, v := range pprops.Value {
-			err := ValidateDraft04Schema(v)
-			if err != nil {
-				return fmt.Errorf("%q must be a valid schema: %s", schemaPath+"/patternProperties/"+k.Value)
-			}
-			re, err := regexp.Compile(k.Value)
-			if err != nil {
-				return fmt.Errorf("%q: %q is not a valid regexp: %s", schemaPath+"/patternProperties", k.Value, err)
-			}
-			for p := range validateWith {
-				if re.MatchString(p) {
-					validateWith[p] = append(validateWith[p], schemaWithPath{v, schemaPath + "/patternProperties/" + k.Value})
-				}
+	}
+	for _, prop := range n.required {
+		if !has(prop) {
+			if err := s.errs.add(newValidationError(path, n.path, "required", params{"property": prop}, "must have property %q", prop)); err != nil {
+				return err
 			}
 		}
 	}
-	x, found = schema.Lookup("additionalProperties")
-	if found {
-		switch ap := x.(type) {
-		case *json.Object:
-			err := ValidateDraft04Schema(ap)
+	return nil
+}
+
+// validateProperty checks property prop of the object at path against
+// "properties", "patternProperties", "additionalProperties" and
+// "propertyNames" of n. The subschemas that apply to the value of the
+// property are passed to apply, along with its path. It returns whether the
+// property counts as evaluated by n.
+func (s *state) validateProperty(n *node, path string, prop string, apply func(sub *node, ppath string) error) (bool, error) {
+	ppath := path + "/" + escapeRefToken(prop)
+	matched := false
+	if sub, found := n.properties[prop]; found {
+		matched = true
+		if err := apply(sub, ppath); err != nil {
+			return false, err
+		}
+	}
+	for _, pp := range n.patternProperties {
+		ok, err := pp.re.MatchString(prop)
+		if err != nil {
+			pattern := pp.re.String()
+			err := s.errs.add(newValidationError(ppath, n.path, "patternProperties", params{"property": prop, "pattern": pattern},
+				"property name can't be matched against regexp %q: %s", pattern, err))
 			if err != nil {
-				return fmt.Errorf("%q must be a valid schema: %s", schemaPath+"/additionalProperties", err)
-			}
-			for k, v := range validateWith {
-				if len(v) == 0 {
-					validateWith[k] = []schemaWithPath{{ap, schemaPath + "/additionalProperties"}}
-				}
+				return false, err
 			}
-		case *json.Bool:
-			if ap.Value == false {
-				for k, v := range validateWith {
-					if len(v) == 0 {
-						return fmt.Errorf("%q is not in %q, is not matched by anything in %q and %q is set to false",
-							path+"/"+k, schemaPath+"/properties", schemaPath+"/patternProperties", schemaPath+"/additionalProperties")
-					}
-				}
+		}
+		if ok {
+			matched = true
+			if err := apply(pp.schema, ppath); err != nil {
+				return false, err
 			}
-		default:
-			return fmt.Errorf("%q must be an object or a boolean", schemaPath+"/additionalProperties")
 		}
 	}
-	for prop, schemas := range validateWith {
-		for _, s := range schemas {
-			err := v.validateAgainstSchema(path+"/"+prop, val.Find(prop), s.path, s.schema)
+	if n.propertyNames != nil {
+		if err := s.checkPart(n.propertyNames, ppath, &json.String{Value: prop}); err != nil {
+			err := s.errs.add(newValidationError(ppath, n.path, "propertyNames", params{"property": prop},
+				"property name is not valid against %q: %s", n.path+"/propertyNames", err))
 			if err != nil {
-				return err
+				return false, err
 			}
 		}
 	}
-	x, found = schema.Lookup("dependencies")
-	if found {
-		deps, ok := x.(*json.Object)
-		if !ok {
-			return fmt.Errorf("%q must be an object", schemaPath+"/dependencies")
-		}
-		for prop, propDeps := range deps.Value {
-			if _, found = val.Lookup(prop.Value); !found {
-				continue
-			}
-			switch deps := propDeps.(type) {
-			case *json.Array:
-				if len(deps.Value) < 1 {
-					return fmt.Errorf("%q must have at least one element", schemaPath+"/dependencies/"+prop.Value)
-				}
-				for i, item := range deps.Value {
-					req, ok := item.(*json.String)
-					if !ok {
-						return fmt.Errorf("%q must be a string", fmt.Sprintf("%s/dependencies/%s/[%d]", schemaPath, prop.Value, i))
-					}
-					if _, found = val.Lookup(req.Value); !found {
-						return fmt.Errorf("%q: %q requires %q to be also present", path, prop.Value, req.Value)
-					}
-				}
-			case *json.Object:
-				err := ValidateDraft04Schema(deps)
-				if err != nil {
-					return fmt.Errorf("%q must be a valid schema: %s", schemaPath+"/dependencies/"+prop.Value, err)
-				}
-				err = v.validateAgainstSchema(path, val, schemaPath+"/dependencies/"+prop.Value, deps)
-				if err != nil {
-					return err
-				}
-			default:
-				return fmt.Errorf("%q must be an array or an object", schemaPath+"/dependencies/"+prop.Value)
-			}
-		}
+	if matched || n.additionalProperties == nil {
+		return matched, nil
 	}
-	return nil
+	if n.additionalProperties.never {
+		err := s.errs.add(newValidationError(ppath, n.path, "additionalProperties", params{"property": prop},
+			"is not in %q, is not matched by anything in %q and %q is set to false",
+			n.path+"/properties", n.path+"/patternProperties", n.path+"/additionalProperties"))
+		return true, err
+	}
+	return true, apply(n.additionalProperties, ppath)
 }
 
-func (v *Validator) validateNumber(path string, val *json.Number, schemaPath string, schema *json.Object) error {
-	x, found := schema.Lookup("multipleOf")
-	if found {
-		switch div := x.(type) {
-		case *json.Number:
-			if div.Value <= 0 {
-				return fmt.Errorf("%q must be a number and greater than 0", schemaPath+"/multipleOf")
-			}
-			// TODO(imax): find a nice way to handle this for floating point numbers.
-			if val.Value/div.Value != float64(int(val.Value/div.Value)) {
-				return fmt.Errorf("%q must be a multiple of %g", path, div.Value)
-			}
-		case *json.Integer:
-			if div.Value <= 0 {
-				return fmt.Errorf("%q must be a number and greater than 0", schemaPath+"/multipleOf")
-			}
-			// TODO(imax): find a nice way to handle this for floating point numbers.
-			if val.Value/float64(div.Value) != float64(int64(val.Value)/div.Value) {
-				return fmt.Errorf("%q must be a multiple of %d", path, div.Value)
-			}
-		default:
-			return fmt.Errorf("%q must be a number", schemaPath+"/multipleOf")
-		}
-	}
-	x, found = schema.Lookup("maximum")
-	if found {
-		exclude := false
-		y, found := schema.Lookup("exclusiveMaximum")
-		if found {
-			e, ok := y.(*json.Bool)
-			if !ok {
-				return fmt.Errorf("%q must be a boolean", schemaPath+"/exclusiveMaximum")
-			}
-			exclude = e.Value
+func (s *state) validateNumber(n *node, path string, val json.Value) error {
+	if n.multipleOf != nil && !isMultipleOf(val, n.multipleOf) {
+		err := s.errs.add(newValidationError(path, n.path, "multipleOf", params{"multipleOf": numberParam(n.multipleOf)},
+			"must be a multiple of %s", formatNumber(n.multipleOf)))
+		if err != nil {
+			return err
 		}
-		switch max := x.(type) {
-		case *json.Number:
-			if exclude {
-				if val.Value >= max.Value {
-					return fmt.Errorf("%q must be less than %g", path, max.Value)
-				}
-			} else {
-				if val.Value > max.Value {
-					return fmt.Errorf("%q must be less then or equal to %g", path, max.Value)
-				}
-			}
-		case *json.Integer:
-			if exclude {
-				if val.Value >= float64(max.Value) {
-					return fmt.Errorf("%q must be less than %d", path, max.Value)
-				}
-			} else {
-				if val.Value > float64(max.Value) {
-					return fmt.Errorf("%q must be less then or equal to %d", path, max.Value)
-				}
-			}
-		default:
-			return fmt.Errorf("%q must be a number", schemaPath+"/maximum")
-		}
-	}
-	x, found = schema.Lookup("minimum")
-	if found {
-		exclude := false
-		y, found := schema.Lookup("exclusiveMinimum")
-		if found {
-			e, ok := y.(*json.Bool)
-			if !ok {
-				return fmt.Errorf("%q must be a boolean", schemaPath+"/exclusiveMinimum")
-			}
-			exclude = e.Value
+	}
+	if n.maximum != nil && compareNumbers(val, n.maximum) > 0 {
+		err := s.errs.add(newValidationError(path, n.path, "maximum", n.limitParams(n.maximum, false),
+			"must be less then or equal to %s", formatNumber(n.maximum)))
+		if err != nil {
+			return err
 		}
-		switch min := x.(type) {
-		case *json.Number:
-			if exclude {
-				if val.Value <= min.Value {
-					return fmt.Errorf("%q must be greater than %g", path, min.Value)
-				}
-			} else {
-				if val.Value < min.Value {
-					return fmt.Errorf("%q must be greater then or equal to %g", path, min.Value)
-				}
-			}
-		case *json.Integer:
-			if exclude {
-				if val.Value <= float64(min.Value) {
-					return fmt.Errorf("%q must be greater than %d", path, min.Value)
-				}
-			} else {
-				if val.Value < float64(min.Value) {
-					return fmt.Errorf("%q must be greater then or equal to %d", path, min.Value)
-				}
-			}
-		default:
-			return fmt.Errorf("%q must be a number", schemaPath+"/minimum")
+	}
+	if n.exclusiveMaximum != nil && compareNumbers(val, n.exclusiveMaximum) >= 0 {
+		err := s.errs.add(newValidationError(path, n.path, n.exclusiveKeyword("exclusiveMaximum", "maximum"), n.limitParams(n.exclusiveMaximum, true),
+			"must be less than %s", formatNumber(n.exclusiveMaximum)))
+		if err != nil {
+			return err
+		}
+	}
+	if n.minimum != nil && compareNumbers(val, n.minimum) < 0 {
+		err := s.errs.add(newValidationError(path, n.path, "minimum", n.limitParams(n.minimum, false),
+			"must be greater then or equal to %s", formatNumber(n.minimum)))
+		if err != nil {
+			return err
+		}
+	}
+	if n.exclusiveMinimum != nil && compareNumbers(val, n.exclusiveMinimum) <= 0 {
+		err := s.errs.add(newValidationError(path, n.path, n.exclusiveKeyword("exclusiveMinimum", "minimum"), n.limitParams(n.exclusiveMinimum, true),
+			"must be greater than %s", formatNumber(n.exclusiveMinimum)))
+		if err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-func (v *Validator) validateInteger(path string, val *json.Integer, schemaPath string, schema *json.Object) error {
-	x, found := schema.Lookup("multipleOf")
-	if found {
-		switch div := x.(type) {
-		case *json.Number:
-			if div.Value <= 0 {
-				return fmt.Errorf("%q must be a number and greater than 0", schemaPath+"/multipleOf")
-			}
-			// TODO(imax): find a nice way to handle this for floating point numbers.
-			if float64(val.Value)/div.Value != float64(int(float64(val.Value)/div.Value)) {
-				return fmt.Errorf("%q must be a multiple of %g", path, div.Value)
-			}
-		case *json.Integer:
-			if div.Value <= 0 {
-				return fmt.Errorf("%q must be a number and greater than 0", schemaPath+"/multipleOf")
-			}
-			if val.Value%div.Value != 0 {
-				return fmt.Errorf("%q must be a multiple of %d", path, div.Value)
-			}
-		default:
-			return fmt.Errorf("%q must be a number", schemaPath+"/multipleOf")
-		}
-	}
-	x, found = schema.Lookup("maximum")
-	if found {
-		exclude := false
-		y, found := schema.Lookup("exclusiveMaximum")
-		if found {
-			e, ok := y.(*json.Bool)
-			if !ok {
-				return fmt.Errorf("%q must be a boolean", schemaPath+"/exclusiveMaximum")
-			}
-			exclude = e.Value
+func (s *state) validateUnevaluated(n *node, path string, val json.Value) error {
+	switch val := val.(type) {
+	case *json.Array:
+		if n.unevaluatedItems == nil || s.evaluated.allItems {
+			return nil
 		}
-		switch max := x.(type) {
-		case *json.Number:
-			if exclude {
-				if float64(val.Value) >= max.Value {
-					return fmt.Errorf("%q must be less than %g", path, max.Value)
-				}
-			} else {
-				if float64(val.Value) > max.Value {
-					return fmt.Errorf("%q must be less then or equal to %g", path, max.Value)
-				}
+		for i := s.evaluated.items; i < len(val.Value); i++ {
+			if s.evaluated.indices[i] {
+				continue
 			}
-		case *json.Integer:
-			if exclude {
-				if val.Value >= max.Value {
-					return fmt.Errorf("%q must be less than %d", path, max.Value)
-				}
-			} else {
-				if val.Value > max.Value {
-					return fmt.Errorf("%q must be less then or equal to %d", path, max.Value)
+			ipath := path + "/" + strconv.Itoa(i)
+			if n.unevaluatedItems.never {
+				err := s.errs.add(newValidationError(ipath, n.path, "unevaluatedItems", params{"index": i},
+					"is not evaluated by any of the subschemas and %q is set to false", n.path+"/unevaluatedItems"))
+				if err != nil {
+					return err
 				}
+			} else if err := s.descend(n.unevaluatedItems, ipath, val.Value[i]); err != nil {
+				return err
 			}
-		default:
-			return fmt.Errorf("%q must be a number", schemaPath+"/maximum")
-		}
-	}
-	x, found = schema.Lookup("minimum")
-	if found {
-		exclude := false
-		y, found := schema.Lookup("exclusiveMinimum")
-		if found {
-			e, ok := y.(*json.Bool)
-			if !ok {
-				return fmt.Errorf("%q must be a boolean", schemaPath+"/exclusiveMinimum")
-			}
-			exclude = e.Value
 		}
-		switch min := x.(type) {
-		case *json.Number:
-			if exclude {
-				if float64(val.Value) <= min.Value {
-					return fmt.Errorf("%q must be greater than %g", path, min.Value)
-				}
-			} else {
-				if float64(val.Value) < min.Value {
-					return fmt.Errorf("%q must be greater then or equal to %g", path, min.Value)
-				}
+		s.evaluatedItems(-1)
+	case *json.Object:
+		if n.unevaluatedProperties == nil {
+			return nil
+		}
+		for _, prop := range sortedKeys(val) {
+			if s.evaluated.properties[prop] {
+				continue
 			}
-		case *json.Integer:
-			if exclude {
-				if val.Value <= min.Value {
-					return fmt.Errorf("%q must be greater than %d", path, min.Value)
-				}
-			} else {
-				if val.Value < min.Value {
-					return fmt.Errorf("%q must be greater then or equal to %d", path, min.Value)
+			ppath := path + "/" + escapeRefToken(prop)
+			if n.unevaluatedProperties.never {
+				err := s.errs.add(newValidationError(ppath, n.path, "unevaluatedProperties", params{"property": prop},
+					"is not evaluated by any of the subschemas and %q is set to false", n.path+"/unevaluatedProperties"))
+				if err != nil {
+					return err
 				}
+			} else if err := s.descend(n.unevaluatedProperties, ppath, val.Find(prop)); err != nil {
+				return err
 			}
-		default:
-			return fmt.Errorf("%q must be a number", schemaPath+"/minimum")
+			s.evaluatedProperty(prop)
 		}
 	}
 	return nil
 }
+
+// exclusiveKeyword returns the keyword that sets an exclusive limit: keyword
+// itself, or in draft 4 the limit keyword modified by it.
+func (n *node) exclusiveKeyword(keyword string, limitKeyword string) string {
+	if n.dialect.booleanExclusiveLimits {
+		return limitKeyword
+	}
+	return keyword
+}
+
+// limitParams returns ValidationError.Params for a failed numeric limit. In
+// draft 4 they also say whether the limit is exclusive.
+func (n *node) limitParams(limit json.Value, exclusive bool) params {
+	if n.dialect.booleanExclusiveLimits {
+		return params{"limit": numberParam(limit), "exclusive": exclusive}
+	}
+	return params{"limit": numberParam(limit)}
+}
@@ -1,31 +1,46 @@
 package schema
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	json "github.com/cesanta/ucl"
 	"github.com/fatih/color"
 )
 
-func serveRemotes(t *testing.T, done chan struct{}) {
-	defer close(done)
-	err := http.ListenAndServe("localhost:1234", http.FileServer(http.Dir("schema-tests/remotes")))
+// serveRemotes starts serving remote schemas used by the test suite. It returns
+// once the server is ready to accept connections, since remote references are
+// fetched by NewValidator.
+func serveRemotes(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:1234")
 	if err != nil {
-		t.Log("ListenAndServe failed: %s", err)
+		t.Fatalf("Listen failed: %s", err)
 	}
+	go http.Serve(l, http.FileServer(http.Dir("schema-tests/remotes")))
 }
 
-func testFiles(t *testing.T, files []string, loader *Loader) {
+func testFiles(t *testing.T, files []string, loader *Loader, d *Dialect) {
 	var passing, total, schemaErrors int
 	for _, file := range files {
 		f, err := os.Open(file)
 		if err != nil {
 			t.Fatalf("Failed to open %q: %s", file, err)
 		}
-		v, err := json.Parse(f)
+		v, err := Parse(f)
 		f.Close()
 		if err != nil {
 			t.Fatalf("Failed to parse %q: %s", file, err)
@@ -42,13 +57,18 @@ func testFiles(t *testing.T, files []string, loader *Loader) {
 			}
 			t.Logf(color.BlueString("=====> Testing %s, case %d: %s", file, i, test.Find("description")))
 			schema := test.Find("schema")
-			err := ValidateDraft04Schema(schema)
+			err := d.validateSchema("#", schema)
 			if err != nil {
 				t.Errorf(color.RedString("schema does not pass validation: %s", err))
 				schemaErrors++
 			}
-			v, _ := NewValidator(schema, loader) // not checking the error since schema is already validated
 			cases := test.Find("tests").(*json.Array)
+			v, err := NewValidator(schema, loader, DefaultDialect(d))
+			if err != nil {
+				t.Errorf(color.RedString("failed to create validator: %s", err))
+				total += len(cases.Value)
+				continue
+			}
 			for _, c := range cases.Value {
 				total++
 				case_ := c.(*json.Object)
@@ -75,39 +95,1371 @@ func testFiles(t *testing.T, files []string, loader *Loader) {
 	}
 }
 
+// metaSchemaLoader returns a loader with the given meta-schemas added and
+// network access enabled for the remotes served by serveRemotes.
+func metaSchemaLoader(t *testing.T, files ...string) *Loader {
+	loader := NewLoader()
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatalf("Failed to open %s: %s", file, err)
+		}
+		s, err := Parse(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %s", file, err)
+		}
+		loader.Add(s)
+	}
+	loader.EnableNetworkAccess(true)
+	return loader
+}
+
 func TestCompliance(t *testing.T) {
-	done := make(chan struct{})
-	go serveRemotes(t, done)
-	f, err := os.Open("draft04schema.json")
+	serveRemotes(t)
+	loader := metaSchemaLoader(t, "draft04schema.json", "draft06schema.json", "draft07schema.json",
+		"draft201909schema.json", "draft201909core.json", "draft201909applicator.json", "draft201909validation.json",
+		"draft201909metadata.json", "draft201909format.json", "draft201909content.json",
+		"draft202012schema.json", "draft202012core.json", "draft202012applicator.json", "draft202012unevaluated.json",
+		"draft202012validation.json", "draft202012metadata.json", "draft202012formatannotation.json", "draft202012content.json")
+
+	for _, tc := range []struct {
+		dir     string
+		dialect *Dialect
+	}{
+		{"schema-tests/tests/draft4", Draft04},
+		{"schema-tests/tests/draft6", Draft06},
+		{"schema-tests/tests/draft7", Draft07},
+		{"schema-tests/tests/draft2019-09", Draft201909},
+		{"schema-tests/tests/draft2020-12", Draft202012},
+	} {
+		t.Run(tc.dialect.String(), func(t *testing.T) {
+			files, err := filepath.Glob(tc.dir + "/*.json")
+			if err != nil || len(files) == 0 {
+				t.Fatalf("Test files not found in %s: %v", tc.dir, err)
+			}
+			testFiles(t, files, loader, tc.dialect)
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	testFiles(t, []string{"schema-tests/tests/draft4/optional/format.json"}, nil, Draft04)
+}
+
+func TestBignum(t *testing.T) {
+	testFiles(t, []string{"schema-tests/tests/draft4/optional/bignum.json"}, nil, Draft04)
+}
+
+func TestZeroTerminatedFloats(t *testing.T) {
+	testFiles(t, []string{"schema-tests/tests/draft4/optional/zeroTerminatedFloats.json"}, nil, Draft04)
+}
+
+func TestDialects(t *testing.T) {
+	for _, tc := range []struct {
+		schema  string
+		data    string
+		keyword string // Empty if data is valid.
+	}{
+		// Without "$schema" it is draft 04.
+		{`{"maximum": 1, "exclusiveMaximum": true}`, `1`, "maximum"},
+		{`{"type": "integer"}`, `1.0`, "type"},
+		{`{"const": 1}`, `2`, ""},
+		{`{"$schema": "http://json-schema.org/draft-04/schema#", "minimum": 1, "exclusiveMinimum": true}`, `1`, "minimum"},
+
+		{`{"$schema": "http://json-schema.org/draft-06/schema#", "exclusiveMaximum": 1}`, `1`, "exclusiveMaximum"},
+		{`{"$schema": "http://json-schema.org/draft-06/schema#", "exclusiveMinimum": 1}`, `1.5`, ""},
+		{`{"$schema": "http://json-schema.org/draft-06/schema#", "type": "integer"}`, `1.0`, ""},
+		{`{"$schema": "http://json-schema.org/draft-06/schema#", "const": {"a": [1]}}`, `{"a": [2]}`, "const"},
+		{`{"$schema": "http://json-schema.org/draft-06/schema#", "contains": {"type": "string"}}`, `[1, 2]`, "contains"},
+		{`{"$schema": "http://json-schema.org/draft-06/schema#", "propertyNames": {"maxLength": 2}}`, `{"ab": 1, "abc": 2}`, "propertyNames"},
+		{`{"$schema": "http://json-schema.org/draft-06/schema#", "properties": {"a": false}}`, `{"a": 1}`, "false"},
+		{`{"$schema": "http://json-schema.org/draft-06/schema#", "items": true, "required": []}`, `[1]`, ""},
+		// "if" is not a keyword before draft 07.
+		{`{"$schema": "http://json-schema.org/draft-06/schema#", "if": true, "then": false}`, `1`, ""},
+
+		{`{"$schema": "http://json-schema.org/draft-07/schema#", "if": {"type": "string"}, "then": {"minLength": 2}, "else": {"minimum": 2}}`, `"a"`, "minLength"},
+		{`{"$schema": "http://json-schema.org/draft-07/schema#", "if": {"type": "string"}, "then": {"minLength": 2}, "else": {"minimum": 2}}`, `1`, "minimum"},
+		{`{"$schema": "http://json-schema.org/draft-07/schema#", "if": {"type": "string"}, "then": {"minLength": 2}}`, `1`, ""},
+		{`{"$schema": "http://json-schema.org/draft-07/schema#", "then": false, "else": false}`, `1`, ""},
+		{`{"$schema": "http://json-schema.org/draft-07/schema#", "readOnly": true, "contentMediaType": "application/json", "contentEncoding": "base64"}`, `"!"`, ""},
+		{`{"$schema": "http://json-schema.org/draft-07/schema#", "format": "date"}`, `"2018-02-30"`, "format"},
+		{`{"$schema": "http://json-schema.org/draft-07/schema#", "format": "json-pointer"}`, `"/a~2"`, "format"},
+		// "$ref" overrides its siblings before 2019-09.
+		{`{"$schema": "http://json-schema.org/draft-07/schema#", "$ref": "#/definitions/a", "maximum": 1, "definitions": {"a": {}}}`, `2`, ""},
+
+		{`{"$schema": "https://json-schema.org/draft/2019-09/schema", "$ref": "#/$defs/a", "maximum": 1, "$defs": {"a": {}}}`, `2`, "maximum"},
+		{`{"$schema": "https://json-schema.org/draft/2019-09/schema", "allOf": [{"properties": {"a": true}}], "unevaluatedProperties": false}`, `{"a": 1, "b": 2}`, "unevaluatedProperties"},
+		{`{"$schema": "https://json-schema.org/draft/2019-09/schema", "anyOf": [{"items": [true]}, {"items": [true, true]}], "unevaluatedItems": false}`, `[1, 2, 3]`, "unevaluatedItems"},
+		{`{"$schema": "https://json-schema.org/draft/2019-09/schema", "dependentRequired": {"a": ["b"]}}`, `{"a": 1}`, "dependentRequired"},
+		// "dependencies" is not a keyword since 2019-09.
+		{`{"$schema": "http://json-schema.org/draft-07/schema#", "dependencies": {"a": ["b"]}}`, `{"a": 1}`, "dependencies"},
+		{`{"$schema": "https://json-schema.org/draft/2019-09/schema", "dependencies": {"a": ["b"]}}`, `{"a": 1}`, ""},
+		{`{"$schema": "https://json-schema.org/draft/2020-12/schema", "dependencies": {"a": false}}`, `{"a": 1}`, ""},
+		{`{"$schema": "https://json-schema.org/draft/2019-09/schema", "contains": {"type": "string"}, "minContains": 2}`, `["a", 1]`, "minContains"},
+		{`{"$schema": "https://json-schema.org/draft/2019-09/schema", "contains": {"type": "string"}, "maxContains": 1}`, `["a", "b"]`, "maxContains"},
+		// "format" is only an annotation since 2019-09.
+		{`{"$schema": "https://json-schema.org/draft/2019-09/schema", "format": "date"}`, `"2018-02-30"`, ""},
+
+		{`{"$schema": "https://json-schema.org/draft/2020-12/schema", "prefixItems": [{"type": "integer"}], "items": false}`, `[1, 2]`, "items"},
+		{`{"$schema": "https://json-schema.org/draft/2020-12/schema", "prefixItems": [{"type": "integer"}]}`, `["a"]`, "type"},
+		{`{"$schema": "https://json-schema.org/draft/2020-12/schema", "prefixItems": [true], "additionalItems": false}`, `[1, 2]`, ""},
+		{`{"$schema": "https://json-schema.org/draft/2020-12/schema", "contains": {"type": "string"}, "unevaluatedItems": false}`, `["a", 1]`, "unevaluatedItems"},
+		{`{"$schema": "https://json-schema.org/draft/2020-12/schema", "$dynamicAnchor": "a", "items": {"$dynamicRef": "#a"}, "type": "array"}`, `[[], [1]]`, "type"},
+		{`{"$schema": "https://json-schema.org/draft/2020-12/schema", "format": "date"}`, `"2018-02-30"`, ""},
+
+		// Embedded resources can use a different dialect, but only if they
+		// have an id.
+		{`{"properties": {"a": {"$id": "http://example.com/a", "$schema": "http://json-schema.org/draft-07/schema#", "exclusiveMaximum": 1}}}`, `{"a": 1}`, "exclusiveMaximum"},
+		{`{"$schema": "https://json-schema.org/draft/2020-12/schema", "properties": {"a": {"id": "http://example.com/a", "$schema": "http://json-schema.org/draft-04/schema#", "type": "integer"}}}`, `{"a": 1.0}`, "type"},
+		{`{"$schema": "http://json-schema.org/draft-07/schema#", "properties": {"a": {"$schema": "http://json-schema.org/draft-04/schema#", "exclusiveMaximum": 1}}}`, `{"a": 1}`, "exclusiveMaximum"},
+	} {
+		v, err := NewValidator(mustParse(t, tc.schema), nil)
+		if err != nil {
+			t.Errorf("%s: failed to create validator: %s", tc.schema, err)
+			continue
+		}
+		err = v.Validate(mustParse(t, tc.data))
+		var ve *ValidationError
+		switch {
+		case tc.keyword == "" && err != nil:
+			t.Errorf("%s: validation of %s failed: %s", tc.schema, tc.data, err)
+		case tc.keyword != "" && !errors.As(err, &ve):
+			t.Errorf("%s: validation of %s returned %v, expected a *ValidationError", tc.schema, tc.data, err)
+		case tc.keyword != "" && ve.Keyword != tc.keyword:
+			t.Errorf("%s: validation of %s failed on %q, expected %q", tc.schema, tc.data, ve.Keyword, tc.keyword)
+		}
+	}
+
+	if _, err := NewValidator(mustParse(t, `true`), nil); err == nil {
+		t.Errorf("NewValidator accepted a boolean schema in draft 04")
+	}
+	v, err := NewValidator(mustParse(t, `false`), nil, DefaultDialect(Draft06))
 	if err != nil {
-		t.Fatalf("Failed to open draft04schema.json: %s", err)
+		t.Fatalf("Failed to create validator: %s", err)
 	}
-	s, err := json.Parse(f)
-	f.Close()
+	var ve *ValidationError
+	if err := v.Validate(mustParse(t, `1`)); !errors.As(err, &ve) || ve.Keyword != "false" || ve.SchemaPath != "#" {
+		t.Errorf("Validation against false returned %#v", err)
+	}
+}
+
+func TestOverrideDialect(t *testing.T) {
+	for _, name := range []string{"draft-04", "http://json-schema.org/draft-04/schema", "http://json-schema.org/draft-04/schema#"} {
+		if d := DialectByName(name); d != Draft04 {
+			t.Errorf("DialectByName(%q) returned %v", name, d)
+		}
+	}
+	if d := DialectByName("draft-05"); d != nil {
+		t.Errorf("DialectByName returned %v for an unknown dialect", d)
+	}
+
+	schema := mustParse(t, `{"$schema": "http://json-schema.org/draft-07/schema#", "type": "integer"}`)
+	v, err := NewValidator(schema, nil, OverrideDialect(Draft04))
 	if err != nil {
-		t.Fatalf("Failed to parse draft04schema.json: %s", err)
+		t.Fatalf("Failed to create validator: %s", err)
+	}
+	var ve *ValidationError
+	if err := v.Validate(mustParse(t, `1.0`)); !errors.As(err, &ve) || ve.Keyword != "type" {
+		t.Errorf("Validation of 1.0 as draft 04 integer returned %v", err)
 	}
+	if _, err := NewValidator(mustParse(t, `true`), nil, OverrideDialect(Draft04), DefaultDialect(Draft07)); err == nil {
+		t.Errorf("NewValidator accepted a boolean schema with draft 04 forced")
+	}
+}
+
+func TestVocabulary(t *testing.T) {
+	loader := NewLoader()
+	loader.AddAs(mustParse(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$vocabulary": {
+			"https://json-schema.org/draft/2020-12/vocab/core": true,
+			"https://json-schema.org/draft/2020-12/vocab/applicator": true,
+			"http://example.com/vocab/optional": false
+		}
+	}`), "http://example.com/no-validation")
+	loader.AddAs(mustParse(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$vocabulary": {
+			"https://json-schema.org/draft/2020-12/vocab/core": true,
+			"http://example.com/vocab/required": true
+		}
+	}`), "http://example.com/unknown-vocabulary")
+
+	v, err := NewValidator(mustParse(t, `{
+		"$schema": "http://example.com/no-validation",
+		"properties": {"a": false},
+		"maximum": 1
+	}`), loader)
+	if err != nil {
+		t.Fatalf("Failed to create validator: %s", err)
+	}
+	if err := v.Validate(mustParse(t, `2`)); err != nil {
+		t.Errorf("Keyword from the validation vocabulary was used: %s", err)
+	}
+	var ve *ValidationError
+	if err := v.Validate(mustParse(t, `{"a": 1}`)); !errors.As(err, &ve) || ve.Keyword != "false" {
+		t.Errorf("Keyword from the applicator vocabulary was not used: %v", err)
+	}
+
+	if _, err := NewValidator(mustParse(t, `{"$schema": "http://example.com/unknown-vocabulary"}`), loader); err == nil {
+		t.Errorf("NewValidator accepted a meta-schema with an unknown required vocabulary")
+	}
+
+	// Meta-schemas that can't be used are errors, unless the dialect is
+	// given explicitly.
+	loader.AddAs(mustParse(t, `{"type": "object"}`), "http://example.com/no-dialect")
+	for _, uri := range []string{"http://example.com/missing", "http://example.com/no-dialect"} {
+		s := mustParse(t, fmt.Sprintf(`{"$schema": %q, "maximum": 1}`, uri))
+		if _, err := NewValidator(s, loader, DefaultDialect(Draft07)); err == nil || !strings.Contains(err.Error(), uri) {
+			t.Errorf("NewValidator with meta-schema %q returned %v", uri, err)
+		}
+		v, err := NewValidator(s, loader, OverrideDialect(Draft07))
+		if err != nil {
+			t.Fatalf("Failed to create validator with meta-schema %q: %s", uri, err)
+		}
+		if err := v.Validate(mustParse(t, `2`)); !errors.As(err, &ve) || ve.Keyword != "maximum" {
+			t.Errorf("Validation with meta-schema %q returned %v", uri, err)
+		}
+	}
+}
+
+func mustParse(t *testing.T, s string) json.Value {
+	v, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatalf("Failed to parse %q: %s", s, err)
+	}
+	return v
+}
+
+func TestValidateAll(t *testing.T) {
+	schema := mustParse(t, `{
+		"properties": {
+			"a": {"type": "string"},
+			"b": {"maxLength": 2},
+			"c": {"items": {"type": "integer"}}
+		},
+		"required": ["d"]
+	}`)
+	v, err := NewValidator(schema, nil)
+	if err != nil {
+		t.Fatalf("Failed to create validator: %s", err)
+	}
+	data := mustParse(t, `{"a": 1, "b": "foo", "c": [1, "x", "y"]}`)
+
+	if err := v.Validate(data); err == nil {
+		t.Errorf("Validate succeeded, expected an error")
+	} else if _, ok := err.(Errors); ok {
+		t.Errorf("Validate returned Errors, expected a single error")
+	}
+
+	err = v.ValidateAll(data, 0)
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatalf("ValidateAll returned %T, expected Errors", err)
+	}
+	if len(errs) != 5 {
+		t.Errorf("ValidateAll returned %d errors, expected 5:\n%s", len(errs), errs)
+	}
+
+	err = v.ValidateAll(data, 2)
+	if errs, ok := err.(Errors); !ok || len(errs) != 2 {
+		t.Errorf("ValidateAll with a limit of 2 returned %#v", err)
+	}
+
+	if err := v.ValidateAll(mustParse(t, `{"d": 1}`), 0); err != nil {
+		t.Errorf("ValidateAll failed on valid data: %s", err)
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	schema := mustParse(t, `{
+		"definitions": {"short": {"maxLength": 2}},
+		"properties": {"a/b": {"items": {"$ref": "#/definitions/short"}}}
+	}`)
+	v, err := NewValidator(schema, nil)
+	if err != nil {
+		t.Fatalf("Failed to create validator: %s", err)
+	}
+	err = v.Validate(mustParse(t, `{"a/b": ["ok", "long"]}`))
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("Validate returned %#v, expected a *ValidationError", err)
+	}
+	if ve.InstancePath != "/a~1b/1" {
+		t.Errorf("InstancePath is %q, expected %q", ve.InstancePath, "/a~1b/1")
+	}
+	if ve.SchemaPath != "#/definitions/short/maxLength" {
+		t.Errorf("SchemaPath is %q, expected %q", ve.SchemaPath, "#/definitions/short/maxLength")
+	}
+	if ve.Keyword != "maxLength" || ve.Params["limit"] != 2 {
+		t.Errorf("Keyword is %q and params are %v, expected maxLength with limit 2", ve.Keyword, ve.Params)
+	}
+
+	err = v.ValidateAll(mustParse(t, `{"a/b": ["long"]}`), 0)
+	if !errors.As(err, &ve) || ve.Keyword != "maxLength" {
+		t.Errorf("errors.As failed to find *ValidationError in %#v", err)
+	}
+}
+
+func BenchmarkValidate(b *testing.B) {
+	schema, err := json.Parse(strings.NewReader(`{
+		"definitions": {
+			"item": {
+				"properties": {
+					"id": {"type": "integer", "minimum": 0},
+					"name": {"type": "string", "pattern": "^[a-z]+$"},
+					"tags": {"type": "array", "items": {"type": "string"}, "uniqueItems": true}
+				},
+				"required": ["id", "name"]
+			}
+		},
+		"type": "array",
+		"items": {"$ref": "#/definitions/item"}
+	}`))
+	if err != nil {
+		b.Fatalf("Failed to parse schema: %s", err)
+	}
+	data, err := json.Parse(strings.NewReader(`[
+		{"id": 1, "name": "foo", "tags": ["a", "b"]},
+		{"id": 2, "name": "bar", "tags": []},
+		{"id": 3, "name": "baz"}
+	]`))
+	if err != nil {
+		b.Fatalf("Failed to parse data: %s", err)
+	}
+	v, err := NewValidator(schema, nil)
+	if err != nil {
+		b.Fatalf("Failed to create validator: %s", err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := v.Validate(data); err != nil {
+			b.Fatalf("Validation failed: %s", err)
+		}
+	}
+}
+
+func TestRefCycles(t *testing.T) {
+	for _, s := range []string{
+		`{"$ref": "#"}`,
+		`{"definitions": {"a": {"$ref": "#/definitions/b"}, "b": {"allOf": [{"$ref": "#/definitions/a"}]}}, "properties": {"foo": {"$ref": "#/definitions/a"}}}`,
+		`{"anyOf": [{"type": "string"}, {"not": {"$ref": "#"}}]}`,
+	} {
+		if _, err := NewValidator(mustParse(t, s), nil); err == nil {
+			t.Errorf("NewValidator succeeded for %s, expected an error", s)
+		} else {
+			t.Logf("%s: %s", s, err)
+		}
+	}
+
+	// Recursion that goes deeper into the value is fine.
+	list := mustParse(t, `{
+		"definitions": {"node": {"type": ["object", "null"], "properties": {"value": {"type": "integer"}, "next": {"$ref": "#/definitions/node"}}}},
+		"$ref": "#/definitions/node"
+	}`)
+	v, err := NewValidator(list, nil)
+	if err != nil {
+		t.Fatalf("Failed to create validator: %s", err)
+	}
+	data := "null"
+	for i := 0; i < 1000; i++ {
+		data = fmt.Sprintf(`{"value": %d, "next": %s}`, i, data)
+	}
+	if err := v.Validate(mustParse(t, data)); err != nil {
+		t.Errorf("Validation failed: %s", err)
+	}
+	if err := v.Validate(mustParse(t, `{"value": 1, "next": {"value": "two", "next": null}}`)); err == nil {
+		t.Errorf("Validation succeeded, expected an error")
+	}
+}
+
+func TestExactNumbers(t *testing.T) {
+	for _, tc := range []struct {
+		schema  string
+		valid   []string
+		invalid []string
+	}{
+		{`{"multipleOf": 0.01}`, []string{`19.99`, `0.07`, `1e300`, `5`}, []string{`0.075`, `19.991`}},
+		{`{"multipleOf": 0.0001}`, []string{`0.0075`, `12391239123`}, []string{`0.00751`}},
+		{`{"multipleOf": 3}`, []string{`9007199254740993`}, []string{`9007199254740992`, `0.3`}},
+		{`{"maximum": 9007199254740992}`, []string{`9007199254740992`, `9007199254740991`}, []string{`9007199254740993`}},
+		{`{"minimum": 0.1, "exclusiveMaximum": 0.3}`, []string{`0.1`, `0.29999999999999`}, []string{`0.09999999999999`, `0.3`}},
+		{`{"enum": [9007199254740993, 0.3]}`, []string{`9007199254740993`, `0.3`}, []string{`9007199254740992`, `9007199254740992.0`, `0.30000000000000004`}},
+		{`{"const": 1}`, []string{`1`, `1.0`}, []string{`1.0000000000000002`}},
+		{`{"uniqueItems": true}`, []string{`[9007199254740992, 9007199254740993]`, `[0.1, 0.30000000000000004]`}, []string{`[9007199254740992, 9007199254740992.0]`}},
+		// Beyond int64 and float64.
+		{`{"maximum": 18446744073709551616}`, []string{`18446744073709551616`, `18446744073709551615`}, []string{`18446744073709551617`}},
+		{`{"const": 18446744073709551617}`, []string{`18446744073709551617`, `18446744073709551617.0`, `1.8446744073709551617e19`}, []string{`18446744073709551616`, `18446744073709551618`}},
+		{`{"multipleOf": 2}`, []string{`18446744073709551618`}, []string{`18446744073709551617`}},
+		{`{"type": "integer"}`, []string{`18446744073709551617`, `1e400`}, []string{`18446744073709551617.5`, `"1"`}},
+		{`{"$schema": "http://json-schema.org/draft-04/schema#", "type": "integer"}`, []string{`18446744073709551617`}, []string{`18446744073709551617.0`}},
+		{`{"uniqueItems": true}`, []string{`[18446744073709551617, 18446744073709551616]`}, []string{`[18446744073709551617, 18446744073709551617.0]`}},
+		{`{"maximum": 1e308}`, []string{`1e308`, `9.99999999999999999999e307`}, []string{`1.0000000000000000001e308`, `1e309`}},
+		{`{"exclusiveMinimum": -1e308}`, []string{`-9.99999999999999999999e307`}, []string{`-1e308`, `-1.00000000000000000001e308`}},
+		// Money, with more significant digits than float64 holds.
+		{`{"multipleOf": 0.01}`, []string{`1234567890123456.78`, `9999999999999999.99`}, []string{`1234567890123456.789`}},
+		{`{"maximum": 1234567890123456.78}`, []string{`1234567890123456.78`, `1234567890123456.77`}, []string{`1234567890123456.79`}},
+		{`{"enum": [1234567890123456.78]}`, []string{`1234567890123456.78`, `1234567890123456.780`}, []string{`1234567890123456.79`, `1234567890123456.8`}},
+		{`{"uniqueItems": true}`, []string{`[1234567890123456.78, 1234567890123456.79]`}, []string{`[1234567890123456.78, 1234567890123456.780]`}},
+	} {
+		v, err := NewValidator(mustParse(t, tc.schema), nil, DefaultDialect(Draft202012))
+		if err != nil {
+			t.Fatalf("Failed to create validator for %s: %s", tc.schema, err)
+		}
+		for _, s := range tc.valid {
+			if err := v.Validate(mustParse(t, s)); err != nil {
+				t.Errorf("%s: %s is not valid: %s", tc.schema, s, err)
+			}
+		}
+		for _, s := range tc.invalid {
+			if err := v.Validate(mustParse(t, s)); err == nil {
+				t.Errorf("%s: %s is valid, expected an error", tc.schema, s)
+			}
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	v := mustParse(t, `{"a": [18446744073709551617, 1.5, 2]}`).(*json.Object).Find("a").(*json.Array)
+	if d, ok := v.Value[0].(*Decimal); !ok || d.Text != "18446744073709551617" {
+		t.Errorf("Expected *Decimal 18446744073709551617, got %T %s", v.Value[0], v.Value[0])
+	}
+	if _, ok := v.Value[1].(*json.Number); !ok {
+		t.Errorf("Expected *json.Number, got %T", v.Value[1])
+	}
+	if _, ok := v.Value[2].(*json.Integer); !ok {
+		t.Errorf("Expected *json.Integer, got %T", v.Value[2])
+	}
+
+	// Whatever is not JSON is up to json.Parse.
+	for _, s := range []string{
+		`{"a": 1,}`,
+		`{a: 1}`,
+		"# comment\n{\"a\": 1}",
+		`[1] [2]`,
+		`1e99999999999`,
+		``,
+	} {
+		want, wantErr := json.Parse(strings.NewReader(s))
+		got, err := Parse(strings.NewReader(s))
+		switch {
+		case (err == nil) != (wantErr == nil):
+			t.Errorf("%q: got error %v, json.Parse returned %v", s, err, wantErr)
+		case err == nil && got.String() != want.String():
+			t.Errorf("%q: got %s, json.Parse returned %s", s, got, want)
+		}
+	}
+}
+
+func TestECMAScript(t *testing.T) {
+	engine := ECMAScript(0)
+	for _, tc := range []struct {
+		pattern string
+		value   string
+		match   bool
+	}{
+		{`^abc$`, "abc", true},
+		{`^abc$`, "abc\n", false},
+		{`^\d+$`, "42", true},
+		{`^\d+$`, "৪২", false},
+		{`^\p{digit}+$`, "৪২", true},
+		{`\wcole`, "école", false},
+		{`\p{Letter}cole`, "école", true},
+		{`^\p{Script=Greek}+$`, "αβγ", true},
+		{`^\P{L}$`, "1", true},
+		{`^\s$`, "\u2003", true},
+		{`^\S$`, "\u00a0", false},
+		{`^\cC$`, "\u0003", true},
+		{`^\u{1F600}\uD83D\uDE00$`, "😀😀", true},
+		{`^[^\W\d]+$`, "abc_", true},
+		{`^[^\W\d]+$`, "ab1", false},
+		{`^[\w-]+$`, "a-b", true},
+		{`^.$`, "\n", false},
+		{`^(?=.*\d)(?=.*[a-z]).{6,}$`, "abc123", true},
+		{`^(?=.*\d)(?=.*[a-z]).{6,}$`, "abcdef", false},
+		{`^(?!foo)\w+$`, "foobar", false},
+		{`(?<=\$)\d+`, "$42", true},
+		{`(?<!\$)\b\d+`, "$42", false},
+		{`^(\w+) \1$`, "hey hey", true},
+		{`^(\w+) \1$`, "hey you", false},
+		{`^(?<q>['"]).*\k<q>$`, `"quoted"`, true},
+		{`^(?<q>['"]).*\k<q>$`, `"quoted'`, false},
+		{`^(a|ab)(c|bcd)(d*)$`, "abcd", true},
+		{`^(?:a*)*b$`, "aaab", true},
+		{`^(a+?)a$`, "aa", true},
+		{`^a{2,3}$`, "aaaa", false},
+		{`\bfoo\b`, "a foo b", true},
+		{`\Bfoo`, "a foo", false},
+	} {
+		re, err := engine.Compile(tc.pattern)
+		if err != nil {
+			t.Errorf("Failed to compile %q: %s", tc.pattern, err)
+			continue
+		}
+		if match, err := re.MatchString(tc.value); err != nil || match != tc.match {
+			t.Errorf("%q matching %q returned %v, %v, expected %v", tc.pattern, tc.value, match, err, tc.match)
+		}
+	}
+
+	for _, pattern := range []string{`\a`, `\Z`, `a{`, `a{2,1}`, `(`, `a)`, `]`, `*a`, `(?=a)*`, `\1(a)\2`, `\k<x>`, `[z-a]`, `[\d-z]`, `\p{Nope}`, `\u12`, `(?<a>x)(?<a>y)`} {
+		if _, err := engine.Compile(pattern); err == nil {
+			t.Errorf("Compiled %q, expected an error", pattern)
+		}
+	}
+
+	re, err := ECMAScript(10 * time.Millisecond).Compile(`^(a+)+$`)
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	if _, err := re.MatchString(strings.Repeat("a", 40) + "!"); err == nil {
+		t.Errorf("Catastrophic backtracking finished before the timeout")
+	}
+
+	// Long strings must not run out of stack, even with quantifiers that keep
+	// a position to backtrack to for every iteration.
+	long := strings.Repeat("a", 5000000)
+	for _, tc := range []struct {
+		pattern string
+		value   string
+		match   bool
+	}{
+		{`^a*$`, long, true},
+		{`^a*?$`, long, true},
+		{`^[a-z]+\d{0,3}$`, long + "123", true},
+		{`^.*b$`, long, false},
+		{`^(a)*$`, long, true},
+		{`^(?:ab)*$`, strings.Repeat("ab", 500000), true},
+		{`^(?:a|b)+?$`, long[:1000000], true},
+		{`^(a)*\1$`, long[:1000000], true},
+		{`^(?:(?=a)a){3,}$`, long + "b", false},
+	} {
+		re, err := ECMAScript(time.Minute).Compile(tc.pattern)
+		if err != nil {
+			t.Fatalf("Failed to compile %q: %s", tc.pattern, err)
+		}
+		if match, err := re.MatchString(tc.value); err != nil || match != tc.match {
+			t.Errorf("%q matching a long string returned %v, %v, expected %v", tc.pattern, match, err, tc.match)
+		}
+	}
+
+	schema := mustParse(t, `{
+		"properties": {"password": {"pattern": "^(?=.*\\d)(?=.*[a-z])"}},
+		"patternProperties": {"^(?!x-)": {"type": "string"}}
+	}`)
+	if _, err := NewValidator(schema, nil); err == nil {
+		t.Errorf("NewValidator succeeded with RE2, expected an error")
+	}
+	v, err := NewValidator(schema, nil, WithRegexpEngine(engine))
+	if err != nil {
+		t.Fatalf("Failed to create validator: %s", err)
+	}
+	for s, valid := range map[string]bool{
+		`{"password": "abc123", "x-count": 1}`: true,
+		`{"password": "abcdef"}`:               false,
+		`{"count": 1}`:                         false,
+	} {
+		if err := v.Validate(mustParse(t, s)); (err == nil) != valid {
+			t.Errorf("Validate(%s) returned %v, expected valid: %v", s, err, valid)
+		}
+	}
+}
+
+func TestValidateContext(t *testing.T) {
+	schema := mustParse(t, `{"items": {"$ref": "#"}, "additionalProperties": {"type": "integer"}}`)
+	v, err := NewValidator(schema, nil, WithLimits(Limits{MaxDepth: 3, MaxEvaluations: 100, MaxErrors: 2}))
+	if err != nil {
+		t.Fatalf("Failed to create validator: %s", err)
+	}
+	ctx := context.Background()
+
+	if err := v.ValidateContext(ctx, mustParse(t, `[[[1]], {"a": 1}]`)); err != nil {
+		t.Errorf("ValidateContext failed on valid data: %s", err)
+	}
+	err = v.ValidateContext(ctx, mustParse(t, `{"a": "x"}`))
+	if errs, ok := err.(Errors); !ok || len(errs) != 1 {
+		t.Errorf("ValidateContext returned %#v, expected a single violation", err)
+	}
+
+	for _, tc := range []struct {
+		data string
+		err  error
+		errs int
+	}{
+		{`[[[[1]]]]`, ErrMaxDepth, 0},
+		{`[{"a": "x"}, [[[1]]]]`, ErrMaxDepth, 1},
+		{"[" + strings.Repeat("1,", 100) + "1]", ErrMaxEvaluations, 0},
+		{`{"a": "x", "b": "y", "c": "z"}`, ErrMaxErrors, 2},
+	} {
+		err := v.ValidateContext(ctx, mustParse(t, tc.data))
+		var ie *IncompleteError
+		if !errors.As(err, &ie) || !errors.Is(err, tc.err) {
+			t.Errorf("ValidateContext(%s) returned %v, expected %v", tc.data, err, tc.err)
+		} else if len(ie.Errors) != tc.errs {
+			t.Errorf("ValidateContext(%s) found %d violations, expected %d: %s", tc.data, len(ie.Errors), tc.errs, ie.Errors)
+		}
+	}
+
+	// Exactly what MaxEvaluations counts.
+	for _, tc := range []struct {
+		schema string
+		data   string
+		cost   int
+	}{
+		{`{}`, `[1]`, 1},
+		{`{"items": {}}`, `[1]`, 1 + 1},
+		{`{"type": "array", "items": {"type": "integer", "minimum": 0}}`, `[1, 2, 3]`, 2 + 3*2},
+		{`{"$schema": "http://json-schema.org/draft-06/schema#", "items": false}`, `[1]`, 2 + 1},
+		{`{"$ref": "#/definitions/a", "definitions": {"a": {"type": "integer"}}}`, `1`, 2 + 1},
+		{`{"anyOf": [{"type": "string"}, {"type": "integer"}]}`, `1`, 1 + 2},
+		{`{"enum": [1, 2, 3, 4, 5]}`, `5`, 5},
+		{`{"uniqueItems": true}`, `[1, 2, 3, 4]`, 6},
+		{`{"uniqueItems": true, "maxItems": 1}`, `[1, 2]`, 2},
+	} {
+		for _, limit := range []int{tc.cost, tc.cost - 1} {
+			if limit == 0 {
+				// No limit.
+				continue
+			}
+			v, err := NewValidator(mustParse(t, tc.schema), nil, WithLimits(Limits{MaxEvaluations: limit}))
+			if err != nil {
+				t.Fatalf("Failed to create validator for %s: %s", tc.schema, err)
+			}
+			err = v.ValidateContext(ctx, mustParse(t, tc.data))
+			if stopped := errors.Is(err, ErrMaxEvaluations); stopped != (limit < tc.cost) {
+				t.Errorf("%s with MaxEvaluations %d on %s returned %v", tc.schema, limit, tc.data, err)
+			}
+		}
+	}
+
+	// Violations found in isolation after the limit is hit are not reported.
+	v, err = NewValidator(mustParse(t, `{"items": {"anyOf": [{"items": {"type": "string"}}]}}`), nil, WithLimits(Limits{MaxDepth: 1}))
+	if err != nil {
+		t.Fatalf("Failed to create validator: %s", err)
+	}
+	if err := v.ValidateContext(ctx, mustParse(t, `[["x"]]`)); !errors.Is(err, ErrMaxDepth) || len(err.(*IncompleteError).Errors) != 0 {
+		t.Errorf("ValidateContext returned %v, expected %v without violations", err, ErrMaxDepth)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := v.ValidateContext(ctx, mustParse(t, `[]`)); !errors.Is(err, context.Canceled) {
+		t.Errorf("ValidateContext returned %v, expected %v", err, context.Canceled)
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	validators := make([]*Validator, 8)
+	// The response is held up until every goroutine is about to need the
+	// schema, so that they need it while it is being fetched.
+	var arrivals sync.WaitGroup
+	arrivals.Add(len(validators))
+	var fetches int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		arrivals.Wait()
+		fmt.Fprint(w, `{"type": "integer", "maximum": 10}`)
+	}))
+	defer srv.Close()
 	loader := NewLoader()
-	loader.Add(s)
 	loader.EnableNetworkAccess(true)
+	schema := mustParse(t, fmt.Sprintf(`{"items": {"$ref": %q}}`, srv.URL+"/small.json"))
+	var wg sync.WaitGroup
+	for i := range validators {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			arrivals.Done()
+			var err error
+			if validators[i], err = NewValidator(schema, loader); err != nil {
+				t.Errorf("Failed to create validator: %s", err)
+			}
+			loader.Add(mustParse(t, fmt.Sprintf(`{"id": "http://example.com/%d.json"}`, i)))
+		}(i)
+	}
+	wg.Wait()
+	if fetches != 1 {
+		t.Errorf("The schema was fetched %d times, expected once", fetches)
+	}
 
-	select {
-	case <-done:
-		t.Fatalf("HTTP server died")
-	default:
+	v := validators[0]
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			data := mustParse(t, fmt.Sprintf("[1, %d]", i*2))
+			if err := v.Validate(data); (err == nil) != (i*2 <= 10) {
+				t.Errorf("Validate(%s) returned %v", data, err)
+			}
+		}(i)
 	}
+	wg.Wait()
+}
+
+func TestParallel(t *testing.T) {
+	schema := mustParse(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"items": {
+			"properties": {"id": {"type": "integer"}, "tags": {"items": {"maxLength": 3}}},
+			"patternProperties": {"^x-": {"type": "string"}},
+			"unevaluatedProperties": false
+		}
+	}`)
+	items := []string{}
+	for i := 0; i < 500; i++ {
+		var props []string
+		switch i % 7 {
+		case 0:
+			props = append(props, `"id": "x"`)
+		case 3:
+			props = append(props, `"other": 1`)
+		default:
+			props = append(props, fmt.Sprintf(`"id": %d`, i))
+		}
+		tags := []string{}
+		for j := 0; j < 20; j++ {
+			tags = append(tags, fmt.Sprintf(`"%s"`, strings.Repeat("t", 1+(i+j)%5)))
+		}
+		props = append(props, `"tags": [`+strings.Join(tags, ",")+`]`, `"x-a": "a"`)
+		items = append(items, "{"+strings.Join(props, ",")+"}")
+	}
+	data := mustParse(t, "["+strings.Join(items, ",")+"]")
 
-	files, err := filepath.Glob("schema-tests/tests/draft4/*.json")
+	serial, err := NewValidator(schema, nil)
 	if err != nil {
-		t.Fatalf("Test files not found: %s", err)
+		t.Fatalf("Failed to create validator: %s", err)
+	}
+	parallel, err := NewValidator(schema, nil, Parallel(8), WithLimits(Limits{MaxErrors: 10}))
+	if err != nil {
+		t.Fatalf("Failed to create validator: %s", err)
+	}
+	for _, max := range []int{0, 1, 10, 1000} {
+		want, got := serial.ValidateAll(data, max), parallel.ValidateAll(data, max)
+		if want == nil || got == nil || want.Error() != got.Error() {
+			t.Errorf("ValidateAll with a limit of %d returned:\n%v\nexpected:\n%v", max, got, want)
+		}
+	}
+	if want, got := serial.Validate(data), parallel.Validate(data); want == nil || got == nil || want.Error() != got.Error() {
+		t.Errorf("Validate returned %v, expected %v", got, want)
+	}
+	err = parallel.ValidateContext(context.Background(), data)
+	var ie *IncompleteError
+	if !errors.As(err, &ie) || ie.Err != ErrMaxErrors || ie.Errors.Error() != serial.ValidateAll(data, 10).Error() {
+		t.Errorf("ValidateContext returned %v", err)
 	}
-	testFiles(t, files, loader)
 }
 
-func TestFormat(t *testing.T) {
-	testFiles(t, []string{"schema-tests/tests/draft4/optional/format.json"}, nil)
+func TestValidateReader(t *testing.T) {
+	schema := mustParse(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$defs": {"id": {"type": "integer", "minimum": 1}},
+		"type": "object",
+		"required": ["records", "total"],
+		"properties": {
+			"records": {
+				"items": {
+					"allOf": [{"required": ["id"]}],
+					"properties": {
+						"id": {"$ref": "#/$defs/id"},
+						"kind": {"enum": ["a", "b"]},
+						"point": {"prefixItems": [{"type": "number"}, {"type": "number"}], "items": false},
+						"any": {"anyOf": [{"type": "string"}, {"items": {"type": "integer"}}]}
+					},
+					"propertyNames": {"maxLength": 5},
+					"additionalProperties": false
+				},
+				"maxItems": 3
+			},
+			"total": {"type": "integer"}
+		}
+	}`)
+	v, err := NewValidator(schema, nil)
+	if err != nil {
+		t.Fatalf("Failed to create validator: %s", err)
+	}
+	ctx := context.Background()
+	sorted := func(err error) []string {
+		var r []string
+		if errs, ok := err.(Errors); ok {
+			for _, e := range errs {
+				r = append(r, e.Error())
+			}
+		} else if err != nil {
+			r = append(r, err.Error())
+		}
+		sort.Strings(r)
+		return r
+	}
+	for _, data := range []string{
+		`{"records": [{"id": 1, "kind": "a", "point": [1, 2.5], "any": [1, 2]}], "total": 1}`,
+		`{"records": [{"id": 0, "kind": "c"}, {"point": [1, 2, 3]}, {"id": 2, "longname": 1}, {"id": 3, "any": [1, "x"]}], "x": {"y": [1]}}`,
+		`[]`,
+		`{"records": {"id": 1}, "total": 1.5}`,
+		`{"records": [{"id": 18446744073709551617, "point": [1e400, 0.10000000000000000001]}], "total": 18446744073709551617.5}`,
+	} {
+		want := sorted(v.ValidateAll(mustParse(t, data), 0))
+		got := sorted(v.ValidateReader(ctx, strings.NewReader(data)))
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("ValidateReader(%s) returned:\n%s\nexpected:\n%s", data, strings.Join(got, "\n"), strings.Join(want, "\n"))
+		}
+	}
+
+	for _, data := range []string{`{"records": [}`, `{"total": 1} {}`, `{"total": 1`} {
+		if err := v.ValidateReader(ctx, strings.NewReader(data)); err == nil {
+			t.Errorf("ValidateReader(%s) succeeded, expected a syntax error", data)
+		} else if _, ok := err.(Errors); ok {
+			t.Errorf("ValidateReader(%s) returned violations, expected a syntax error: %s", data, err)
+		}
+	}
+
+	// Records are generated as they are read, they never exist all at once.
+	pr, pw := io.Pipe()
+	go func() {
+		fmt.Fprint(pw, `{"total": 100000, "records": [`)
+		for i := 1; i <= 100000; i++ {
+			if i > 1 {
+				fmt.Fprint(pw, ",")
+			}
+			fmt.Fprintf(pw, `{"id": %d, "kind": "a"}`, i)
+		}
+		fmt.Fprint(pw, "]}")
+		pw.Close()
+	}()
+	err = v.ValidateReader(ctx, pr)
+	if errs, ok := err.(Errors); !ok || len(errs) != 1 || errs[0].(*ValidationError).Keyword != "maxItems" {
+		t.Errorf("ValidateReader returned %v, expected a maxItems violation", err)
+	}
+
+	// Limits apply the same way as with ValidateContext, whether parts of
+	// the value are parsed or not.
+	for _, tc := range []struct {
+		limits Limits
+		data   string
+		err    error
+		errs   int
+	}{
+		{Limits{MaxErrors: 1}, `{"records": [{"id": 0}], "total": "x"}`, ErrMaxErrors, 1},
+		{Limits{MaxDepth: 2}, `{"records": [{"id": 1}], "total": 1}`, ErrMaxDepth, 0},
+		{Limits{MaxDepth: 3}, `{"records": [{"id": 1, "any": [1]}], "total": 1}`, ErrMaxDepth, 0},
+		{Limits{MaxDepth: 4}, `{"records": [{"id": 1, "any": [1]}], "total": 1}`, nil, 0},
+		{Limits{MaxEvaluations: 100}, `{"records": [` + strings.Repeat(`{"id": 1}, `, 99) + `{"id": 1}], "total": 1}`, ErrMaxEvaluations, 0},
+	} {
+		v, err := NewValidator(schema, nil, WithLimits(tc.limits))
+		if err != nil {
+			t.Fatalf("Failed to create validator: %s", err)
+		}
+		err = v.ValidateReader(ctx, strings.NewReader(tc.data))
+		if tc.err == nil {
+			if err != nil {
+				t.Errorf("ValidateReader with %+v returned %v", tc.limits, err)
+			}
+			continue
+		}
+		var ie *IncompleteError
+		if !errors.As(err, &ie) || !errors.Is(err, tc.err) || len(ie.Errors) != tc.errs {
+			t.Errorf("ValidateReader with %+v returned %v, expected %v with %d violations", tc.limits, err, tc.err, tc.errs)
+		}
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := v.ValidateReader(cancelled, strings.NewReader(`{}`)); !errors.Is(err, context.Canceled) {
+		t.Errorf("ValidateReader returned %v, expected %v", err, context.Canceled)
+	}
+
+	// Nesting is limited even without Limits.MaxDepth, whether the value is
+	// streamed or parsed.
+	for _, s := range []string{`{"items": {"$ref": "#"}}`, `{"items": {"$ref": "#"}, "anyOf": [{}]}`} {
+		v, err := NewValidator(mustParse(t, s), nil)
+		if err != nil {
+			t.Fatalf("Failed to create validator: %s", err)
+		}
+		if err := v.ValidateReader(ctx, strings.NewReader(strings.Repeat("[", 10000)+strings.Repeat("]", 10000))); err != nil {
+			t.Errorf("%s: ValidateReader of 10000 nested arrays returned %v", s, err)
+		}
+		for _, n := range []int{10001, 1000000} {
+			err := v.ValidateReader(ctx, strings.NewReader(strings.Repeat("[", n)))
+			if ie := (*IncompleteError)(nil); !errors.As(err, &ie) || !errors.Is(err, ErrMaxDepth) {
+				t.Errorf("%s: ValidateReader of %d nested arrays returned %v, expected %v", s, n, err, ErrMaxDepth)
+			}
+		}
+	}
 }
 
-func TestZeroTerminatedFloats(t *testing.T) {
-	testFiles(t, []string{"schema-tests/tests/draft4/optional/zeroTerminatedFloats.json"}, nil)
+func TestOutput(t *testing.T) {
+	schema := mustParse(t, `{
+		"definitions": {"short": {"maxLength": 2}},
+		"properties": {
+			"a": {"items": {"$ref": "#/definitions/short"}},
+			"b": {"anyOf": [{"type": "integer"}, {"minimum": 10}]},
+			"c": {"not": {"type": "string"}}
+		}
+	}`)
+	v, err := NewValidator(schema, nil)
+	if err != nil {
+		t.Fatalf("Failed to create validator: %s", err)
+	}
+	data := mustParse(t, `{"a": ["ok", "long"], "b": 1.5, "c": 1}`)
+
+	if out := v.FlagOutput(data); out.Valid {
+		t.Errorf("FlagOutput returned valid for invalid data")
+	}
+	if out := v.FlagOutput(mustParse(t, `{}`)); !out.Valid {
+		t.Errorf("FlagOutput returned invalid for valid data")
+	}
+
+	basic := v.BasicOutput(data)
+	if basic.Valid {
+		t.Errorf("BasicOutput returned valid for invalid data")
+	}
+	want := []OutputUnit{
+		{KeywordLocation: "/properties/a/items/$ref/maxLength", AbsoluteKeywordLocation: "#/definitions/short/maxLength", InstanceLocation: "/a/1"},
+		{KeywordLocation: "/properties/b/anyOf", InstanceLocation: "/b"},
+		{KeywordLocation: "/properties/b/anyOf/0/type", InstanceLocation: "/b"},
+		{KeywordLocation: "/properties/b/anyOf/1/minimum", InstanceLocation: "/b"},
+	}
+	if len(basic.Errors) != len(want) {
+		t.Fatalf("BasicOutput returned %d errors, expected %d: %+v", len(basic.Errors), len(want), basic.Errors)
+	}
+	for i, e := range basic.Errors {
+		if e.KeywordLocation != want[i].KeywordLocation || e.AbsoluteKeywordLocation != want[i].AbsoluteKeywordLocation ||
+			e.InstanceLocation != want[i].InstanceLocation || e.Error == "" {
+			t.Errorf("Error %d is %+v, expected %+v", i, e, want[i])
+		}
+	}
+
+	detailed := v.DetailedOutput(data)
+	if detailed.Valid || detailed.KeywordLocation != "" || len(detailed.Errors) != 2 {
+		t.Fatalf("DetailedOutput returned %+v", detailed)
+	}
+	// Single failures are collapsed into their parents.
+	if e := detailed.Errors[0]; e.KeywordLocation != "/properties/a/items/$ref/maxLength" || e.InstanceLocation != "/a/1" {
+		t.Errorf("First error is %+v", e)
+	}
+	if e := detailed.Errors[1]; e.KeywordLocation != "/properties/b/anyOf" || len(e.Errors) != 2 {
+		t.Errorf("Second error is %+v", e)
+	}
+
+	verbose := v.VerboseOutput(data)
+	if verbose.Valid || len(verbose.Errors) != 3 {
+		t.Fatalf("VerboseOutput returned %+v", verbose)
+	}
+	c := verbose.Errors[2]
+	if c.KeywordLocation != "/properties/c" || !c.Valid || len(c.Errors) != 1 || c.Errors[0].KeywordLocation != "/properties/c/not" || c.Errors[0].Valid {
+		t.Errorf("Unit of a valid property is %+v", c)
+	}
+	if out := v.VerboseOutput(mustParse(t, `{}`)); !out.Valid || len(out.Errors) != 0 {
+		t.Errorf("VerboseOutput of valid data returned %+v", out)
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	schema := mustParse(t, `{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"title": "Config",
+		"definitions": {"port": {"type": "integer", "default": 80, "description": "Port to listen on"}},
+		"properties": {
+			"port": {"$ref": "#/definitions/port"},
+			"host": {"title": "Host", "x-help": "Name or address", "anyOf": [{"format": "ipv4", "description": "IPv4"}, {"maxLength": 3, "description": "Short"}]},
+			"debug": {"title": "Debug", "type": "boolean"}
+		}
+	}`)
+	v, err := NewValidator(schema, nil)
+	if err != nil {
+		t.Fatalf("Failed to create validator: %s", err)
+	}
+
+	r := v.Annotate(mustParse(t, `{"port": 8080, "host": "1.2.3.4", "debug": true}`))
+	if !r.Valid() {
+		t.Fatalf("Annotate failed on valid data: %s", r.Errors)
+	}
+	want := map[string][]string{
+		"":       {"title"},
+		"/port":  {"default", "description"},
+		"/host":  {"title", "x-help", "description"},
+		"/debug": {"title"},
+	}
+	check := func(want map[string][]string) {
+		t.Helper()
+		if len(r.Annotations) != len(want) {
+			t.Errorf("Annotations are collected for %d locations, expected %d", len(r.Annotations), len(want))
+		}
+		for path, keywords := range want {
+			got := []string{}
+			for _, a := range r.Annotations[path] {
+				got = append(got, a.Keyword)
+			}
+			if strings.Join(got, " ") != strings.Join(keywords, " ") {
+				t.Errorf("Annotations for %q are %q, expected %q", path, got, keywords)
+			}
+		}
+	}
+	check(want)
+	if a := r.Annotations["/port"][0]; a.SchemaPath != "#/definitions/port/default" || a.Value.(*json.Integer).Value != 80 {
+		t.Errorf("Annotation is %+v", a)
+	}
+
+	// Annotations of schemas that failed are dropped, but not the ones of
+	// valid properties.
+	r = v.Annotate(mustParse(t, `{"port": "http", "host": "localhost", "debug": false}`))
+	if r.Valid() || len(r.Errors) != 2 {
+		t.Errorf("Annotate returned errors %v, expected 2 of them", r.Errors)
+	}
+	check(map[string][]string{"/debug": {"title"}})
+}
+
+func TestApplyDefaults(t *testing.T) {
+	schema := mustParse(t, `{
+		"definitions": {"port": {"type": "integer", "default": 80}},
+		"properties": {
+			"port": {"$ref": "#/definitions/port"},
+			"server": {
+				"default": {},
+				"properties": {"host": {"default": "localhost"}, "tags": {"default": []}},
+				"required": ["host"]
+			},
+			"mode": {"anyOf": [{"properties": {"x": {"default": 1}}}]}
+		}
+	}`)
+	v, err := NewValidator(schema, nil)
+	if err != nil {
+		t.Fatalf("Failed to create validator: %s", err)
+	}
+	data := mustParse(t, `{"server": {"tags": ["a"]}, "mode": {}}`)
+	filled, err := v.ApplyDefaults(data)
+	if err != nil {
+		t.Fatalf("ApplyDefaults failed: %s", err)
+	}
+	want := mustParse(t, `{"port": 80, "server": {"host": "localhost", "tags": ["a"]}, "mode": {}}`)
+	if !equal(filled, want) {
+		t.Errorf("ApplyDefaults returned %s, expected %s", filled, want)
+	}
+	if !equal(data, mustParse(t, `{"server": {"tags": ["a"]}, "mode": {}}`)) {
+		t.Errorf("ApplyDefaults modified its argument: %s", data)
+	}
+
+	filled, err = v.ApplyDefaults(mustParse(t, `{}`))
+	if err != nil {
+		t.Fatalf("ApplyDefaults failed: %s", err)
+	}
+	want = mustParse(t, `{"port": 80, "server": {"host": "localhost", "tags": []}}`)
+	if !equal(filled, want) {
+		t.Errorf("ApplyDefaults returned %s, expected %s", filled, want)
+	}
+	// Defaults are copied, not shared with the schema.
+	filled.(*json.Object).Find("server").(*json.Object).Find("tags").(*json.Array).Value = nil
+	if filled, _ := v.ApplyDefaults(mustParse(t, `{}`)); !equal(filled, want) {
+		t.Errorf("Schema was modified through the filled value: %s", filled)
+	}
+
+	if _, err := v.ApplyDefaults(mustParse(t, `{"port": "80"}`)); err == nil {
+		t.Errorf("ApplyDefaults succeeded on invalid data")
+	}
+}
+
+func TestCoerce(t *testing.T) {
+	schema := mustParse(t, `{
+		"definitions": {"port": {"type": "integer", "maximum": 65535}},
+		"properties": {
+			"port": {"$ref": "#/definitions/port"},
+			"ratio": {"type": "number"},
+			"debug": {"type": "boolean"},
+			"name": {"type": "string"},
+			"none": {"type": "null"},
+			"tags": {"type": "array", "items": {"type": "integer"}},
+			"any": {"anyOf": [{"type": "integer"}]}
+		},
+		"additionalProperties": {"type": ["integer", "boolean"]}
+	}`)
+	v, err := NewValidator(schema, nil)
+	if err != nil {
+		t.Fatalf("Failed to create validator: %s", err)
+	}
+	data := mustParse(t, `{"port": "8080", "ratio": "0.5", "debug": "true", "name": 42, "none": "", "tags": "7", "x": "false"}`)
+	coerced, err := v.Coerce(data)
+	if err != nil {
+		t.Fatalf("Coerce failed: %s", err)
+	}
+	want := mustParse(t, `{"port": 8080, "ratio": 0.5, "debug": true, "name": "42", "none": null, "tags": [7], "x": false}`)
+	if !equal(coerced, want) {
+		t.Errorf("Coerce returned %s, expected %s", coerced, want)
+	}
+
+	// Types in "anyOf" are not used for coercion.
+	for _, s := range []string{`{"port": "http"}`, `{"port": "70000"}`, `{"port": "1.5"}`, `{"debug": "yes"}`, `{"ratio": "NaN"}`, `{"any": "1"}`} {
+		if r, err := v.Coerce(mustParse(t, s)); err == nil {
+			t.Errorf("Coerce succeeded for %s and returned %s", s, r)
+		}
+	}
+
+	// Only strings written like JSON numbers are converted, to integers only
+	// if the dialect says they are integers.
+	for _, tc := range []struct {
+		schema, data, want string
+	}{
+		{`{"type": "number"}`, `"-0.5e3"`, `-0.5e3`},
+		{`{"type": "number"}`, `"0.10000000000000000000001"`, `0.10000000000000000000001`},
+		{`{"type": "number"}`, `"+5"`, `"+5"`},
+		{`{"type": "number"}`, `".5"`, `".5"`},
+		{`{"type": "number"}`, `"05"`, `"05"`},
+		{`{"type": "number"}`, `"0x1p4"`, `"0x1p4"`},
+		{`{"type": "number"}`, `"Inf"`, `"Inf"`},
+		{`{"type": "number"}`, `" 1"`, `" 1"`},
+		{`{"type": "number"}`, `"+0.10000000000000000000001"`, `"+0.10000000000000000000001"`},
+		{`{"type": "integer"}`, `"0x1p4"`, `"0x1p4"`},
+		{`{"type": "integer"}`, `"1.0"`, `"1.0"`},
+		{`{"type": "integer"}`, `"18446744073709551617"`, `18446744073709551617`},
+		{`{"$schema": "http://json-schema.org/draft-06/schema#", "type": "integer"}`, `"1.0"`, `1.0`},
+		{`{"$schema": "http://json-schema.org/draft-06/schema#", "type": "integer"}`, `"1e2"`, `100`},
+		{`{"$schema": "http://json-schema.org/draft-06/schema#", "type": "integer"}`, `"1.5"`, `"1.5"`},
+	} {
+		v, err := NewValidator(mustParse(t, tc.schema), nil)
+		if err != nil {
+			t.Fatalf("Failed to create validator for %s: %s", tc.schema, err)
+		}
+		coerced, _ := v.Coerce(mustParse(t, tc.data))
+		if want := mustParse(t, tc.want); !equal(coerced, want) || coerced.String() != want.String() {
+			t.Errorf("%s: Coerce(%s) returned %s, expected %s", tc.schema, tc.data, coerced, want)
+		}
+	}
+
+	// Properties that can't be matched against "patternProperties" are left
+	// as is, and the error is reported.
+	v, err = NewValidator(mustParse(t, `{"patternProperties": {"^p": {"type": "integer"}}, "additionalProperties": {"type": "integer"}}`),
+		nil, WithRegexpEngine(failingEngine{}))
+	if err != nil {
+		t.Fatalf("Failed to create validator: %s", err)
+	}
+	data = mustParse(t, `{"p": "1"}`)
+	coerced, err = v.Coerce(data)
+	var ve *ValidationError
+	if !errors.As(err, &ve) || ve.Keyword != "patternProperties" {
+		t.Errorf("Coerce returned %v, expected a patternProperties violation", err)
+	}
+	if !equal(coerced, data) {
+		t.Errorf("Coerce returned %s, expected %s", coerced, data)
+	}
+}
+
+// failingEngine compiles regexps that can never be matched.
+type failingEngine struct{}
+
+func (failingEngine) Compile(expr string) (Regexp, error) {
+	return failingRegexp(expr), nil
+}
+
+type failingRegexp string
+
+func (re failingRegexp) MatchString(s string) (bool, error) {
+	return false, errors.New("can't match")
+}
+
+func (re failingRegexp) String() string {
+	return string(re)
+}
+
+// uniqueBy is "x-unique-by": items of an array must have different values of
+// the named property.
+type uniqueBy string
+
+func (uniqueBy) Compile(value json.Value) (CompiledKeyword, error) {
+	s, ok := value.(*json.String)
+	if !ok {
+		return nil, errors.New("must be a string")
+	}
+	return uniqueBy(s.Value), nil
+}
+
+func (k uniqueBy) Validate(c *KeywordContext, val json.Value) {
+	a, ok := val.(*json.Array)
+	if !ok {
+		return
+	}
+	seen := map[string]int{}
+	for i, item := range a.Value {
+		obj, ok := item.(*json.Object)
+		if !ok {
+			continue
+		}
+		if p, found := obj.Lookup(string(k)); found {
+			if j, found := seen[p.String()]; found {
+				c.Error(map[string]interface{}{"duplicates": []int{j, i}}, "items %d and %d have the same %q", j, i, string(k))
+			}
+			seen[p.String()] = i
+		}
+	}
+}
+
+// deprecatedSince is "x-deprecated-since", which only adds an annotation.
+type deprecatedSince struct{}
+
+func (deprecatedSince) Compile(value json.Value) (CompiledKeyword, error) {
+	return deprecatedSince{}, nil
+}
+
+func (deprecatedSince) Validate(c *KeywordContext, val json.Value) {
+	c.Annotate(&json.String{Value: "deprecated since " + c.Value.(*json.String).Value})
+}
+
+func TestCustomKeywords(t *testing.T) {
+	opts := []Option{WithKeyword("x-unique-by", uniqueBy("")), WithKeyword("x-deprecated-since", deprecatedSince{})}
+	schema := mustParse(t, `{
+		"properties": {
+			"users": {"x-unique-by": "id", "items": {"type": "object"}},
+			"old": {"x-deprecated-since": "1.2"}
+		}
+	}`)
+	v, err := NewValidator(schema, nil, opts...)
+	if err != nil {
+		t.Fatalf("Failed to create validator: %s", err)
+	}
+	if err := v.Validate(mustParse(t, `{"users": [{"id": 1}, {"id": 2}]}`)); err != nil {
+		t.Errorf("Validation failed: %s", err)
+	}
+	err = v.ValidateAll(mustParse(t, `{"users": [{"id": 1}, {"id": 2}, {"id": 1}, {"id": 2}]}`), 0)
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("ValidateAll returned %v, expected 2 errors", err)
+	}
+	var ve *ValidationError
+	if !errors.As(errs[0], &ve) || ve.Keyword != "x-unique-by" || ve.InstancePath != "/users" || ve.SchemaPath != "#/properties/users/x-unique-by" {
+		t.Errorf("Error is %#v", errs[0])
+	}
+
+	r := v.Annotate(mustParse(t, `{"old": 1}`))
+	if a := r.Annotations["/old"]; len(a) != 1 || a[0].Keyword != "x-deprecated-since" || a[0].Value.(*json.String).Value != "deprecated since 1.2" {
+		t.Errorf("Annotations are %+v", a)
+	}
+
+	if _, err := NewValidator(mustParse(t, `{"x-unique-by": 1}`), nil, opts...); err == nil {
+		t.Errorf("NewValidator accepted an invalid value of a custom keyword")
+	}
+	if _, err := NewValidator(mustParse(t, `{}`), nil, WithKeyword("maxLength", uniqueBy(""))); err == nil {
+		t.Errorf("NewValidator accepted a custom keyword with a standard name")
+	}
+}
+
+func TestCustomFormats(t *testing.T) {
+	semver := regexp.MustCompile(`^\d+\.\d+\.\d+$`)
+	checkSemver := func(val string) error {
+		if !semver.MatchString(val) {
+			return fmt.Errorf("%q is not a semantic version", val)
+		}
+		return nil
+	}
+	schema := mustParse(t, `{"properties": {"v": {"format": "semver"}, "d": {"format": "date"}, "x": {"format": "unknown"}}}`)
+	v, err := NewValidator(schema, nil, WithFormat("semver", checkSemver))
+	if err != nil {
+		t.Fatalf("Failed to create validator: %s", err)
+	}
+	if err := v.Validate(mustParse(t, `{"v": "1.2.3", "d": "2020-01-01", "x": "anything"}`)); err != nil {
+		t.Errorf("Validation failed: %s", err)
+	}
+	var ve *ValidationError
+	if err := v.Validate(mustParse(t, `{"v": "1.2"}`)); !errors.As(err, &ve) || ve.Keyword != "format" {
+		t.Errorf("Validation of an invalid version returned %v", err)
+	}
+
+	// Formats are only annotations since 2019-09.
+	v, err = NewValidator(mustParse(t, `{"$schema": "https://json-schema.org/draft/2019-09/schema", "format": "semver"}`), nil, WithFormat("semver", checkSemver))
+	if err != nil {
+		t.Fatalf("Failed to create validator: %s", err)
+	}
+	if err := v.Validate(mustParse(t, `"1.2"`)); err != nil {
+		t.Errorf("Validation failed: %s", err)
+	}
+	// Unless AssertFormats is used.
+	v, err = NewValidator(mustParse(t, `{"$schema": "https://json-schema.org/draft/2020-12/schema", "properties": {"v": {"format": "semver"}, "d": {"format": "date"}}}`),
+		nil, WithFormat("semver", checkSemver), AssertFormats())
+	if err != nil {
+		t.Fatalf("Failed to create validator: %s", err)
+	}
+	if err := v.Validate(mustParse(t, `{"v": "1.2.3", "d": "2020-01-01"}`)); err != nil {
+		t.Errorf("Validation failed: %s", err)
+	}
+	for _, s := range []string{`{"v": "1.2"}`, `{"d": "2020-02-30"}`} {
+		if err := v.Validate(mustParse(t, s)); !errors.As(err, &ve) || ve.Keyword != "format" {
+			t.Errorf("Validation of %s returned %v", s, err)
+		}
+	}
+
+	if _, err := NewValidator(schema, nil, WithFormat("semver", checkSemver), UnknownFormats(RejectUnknownFormats)); err == nil {
+		t.Errorf("NewValidator accepted an unknown format")
+	}
+	unknown := []string{}
+	_, err = NewValidator(schema, nil, UnknownFormats(func(path string, format string) error {
+		unknown = append(unknown, path+" "+format)
+		return nil
+	}))
+	if err != nil {
+		t.Fatalf("Failed to create validator: %s", err)
+	}
+	sort.Strings(unknown)
+	if want := []string{"#/properties/v/format semver", "#/properties/x/format unknown"}; fmt.Sprint(unknown) != fmt.Sprint(want) {
+		t.Errorf("Unknown formats are %q, expected %q", unknown, want)
+	}
+}
+
+func TestBuiltinFormats(t *testing.T) {
+	for _, tc := range []struct {
+		format string
+		value  string
+		valid  bool
+	}{
+		{"uri", "http://foo.bar/?baz=qux#quux", true},
+		{"uri", "mailto:John.Doe@example.com", true},
+		{"uri", "urn:oasis:names:specification:docbook:dtd:xml:4.1.2", true},
+		{"uri", "http://-.~_!$&'()*+,;=:%40:80%2f::::::@example.com", true},
+		{"uri", "//foo.bar/?baz=qux#quux", false},
+		{"uri", "abc", false},
+		{"uri", "http:// shouldfail.com", false},
+		{"uri", "bar,baz:foo", false},
+		{"uri", "http://example.com/%zz", false},
+		{"uri", "http://ƒøø.ßår/?∂éœ=πîx#πîüx", false},
+		{"uri-reference", "#frag", true},
+		{"uri-reference", "/abc", true},
+		{"uri-reference", "\\\\WINDOWS\\fileshare", false},
+		{"iri", "http://ƒøø.ßår/?∂éœ=πîx#πîüx", true},
+		{"iri", "http://[2001:0db8:85a3:0000:0000:8a2e:0370:7334]", true},
+		{"iri", "http://2001:0db8:85a3:0000:0000:8a2e:0370:7334", false},
+		{"iri", "/abc", false},
+		{"iri-reference", "//ƒøø.ßår/?∂éœ=πîx#πîüx", true},
+		{"iri-reference", "#ƒrägmen\\t", false},
+		{"uuid", "2EB8AA08-AA98-11EA-B4AA-73B441D16380", true},
+		{"uuid", "2eb8aa08-aa98-11ea-b4aa-73b441d1638", false},
+		{"duration", "P4DT12H30M5S", true},
+		{"duration", "P2W", true},
+		{"duration", "PT36H", true},
+		{"duration", "P", false},
+		{"duration", "PT1D", false},
+		{"duration", "P1Y1D", false},
+		{"duration", "P1", false},
+		{"duration", "P1D2H", false},
+		{"idn-hostname", "실례.테스트", true},
+		{"idn-hostname", "example.com", true},
+		{"idn-hostname", "〮실례.테스트", false},
+		{"idn-hostname", "-> $ chars", false},
+		{"idn-hostname", "-hello", false},
+		{"idn-hostname", "l·l", true},
+		{"idn-hostname", "a·l", false},
+		{"idn-hostname", "α͵S", false},
+		{"idn-hostname", "α͵β", true},
+		{"idn-hostname", "א׳ב", true},
+		{"idn-hostname", "a׳b", false},
+		{"idn-hostname", "def・abc", false},
+		{"idn-hostname", "ぁ・ぁ", true},
+		{"idn-hostname", "٠۰", false},
+		{"idn-hostname", "ـߺ", false},
+		{"idn-email", "실례@실례.테스트", true},
+		{"idn-email", "joe.bloggs@example.com", true},
+		{"idn-email", "2962", false},
+		{"idn-email", "@example.com", false},
+	} {
+		err := formats[tc.format](tc.value)
+		if tc.valid && err != nil {
+			t.Errorf("%s %q: %s", tc.format, tc.value, err)
+		}
+		if !tc.valid && err == nil {
+			t.Errorf("%s %q is valid, expected an error", tc.format, tc.value)
+		}
+	}
 }